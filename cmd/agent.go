@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/proc"
+	"github.com/fynardo/astropath/internal/task"
+	"github.com/fynardo/astropath/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+// agentLaunch describes an agent run started by one of the role commands
+type agentLaunch struct {
+	Label     string // Agent name used in messages, e.g. "Astropath's Claude Analyst agent"
+	Role      string // Role recorded in the usage ledger
+	Task      string
+	TaskFile  string // Context file the role works on
+	Branch    string
+	Prompt    string
+	Resume    string // Agent session to continue, empty to start a new one
+	Streaming bool
+}
+
+// timeoutFlag, maxTurnsFlag, maxCostFlag and maxTokensFlag hold the persistent flags limiting every agent run
+var timeoutFlag time.Duration
+var maxTurnsFlag int
+var maxCostFlag float64
+var maxTokensFlag int64
+
+// activeStep is the running pipeline step, whose limits apply to the agents of its role
+var activeStep *config.Step
+
+// onAgentResult is called after every agent run when set, e.g. by the pipeline to record the sessions of its steps
+var onAgentResult func(launch agentLaunch, result claude.Result)
+
+// renderPrompt executes the prompt template of a role, or its override file, with the given parameters
+func renderPrompt(promptType config.PromptType, params interface{}) (string, error) {
+	return renderRolePrompt(config.Role{PromptType: promptType}, params)
+}
+
+// renderRolePrompt executes the prompt template of a built-in or custom role with the given parameters
+func renderRolePrompt(role config.Role, params interface{}) (string, error) {
+	text, source, err := config.LoadRolePrompt(role)
+	if err != nil {
+		return "", err
+	}
+	prompt, err := renderTemplate(text, params)
+	if err != nil && source != config.BuiltinPromptSource {
+		return "", fmt.Errorf("%s: %v", source, err)
+	}
+	return prompt, err
+}
+
+// renderTemplate executes a prompt template with the given parameters
+func renderTemplate(text string, params interface{}) (string, error) {
+	templ, err := template.New("prompt").Funcs(template.FuncMap{"section": config.SectionName}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing prompt template: %v", err)
+	}
+
+	var buff = bytes.Buffer{}
+	err = templ.Execute(&buff, params)
+	if err != nil {
+		return "", fmt.Errorf("executing prompt template: %v", err)
+	}
+	return buff.String(), nil
+}
+
+// launchAgent runs an agent with the selected backend and checks the output of its role.
+// When the checks fail, the agent is launched again with a corrective prompt, up to --retries times.
+func launchAgent(cmd *cobra.Command, launch agentLaunch) error {
+	role, isRole := config.GetRole(launch.Role)
+	run := launch
+
+	for attempt := 0; ; attempt++ {
+		session, err := runAgentOnce(cmd, run)
+		if err != nil {
+			return err
+		}
+		if !isRole {
+			break
+		}
+
+		problems, err := verifyOutput(role, launch.TaskFile)
+		if err != nil {
+			return err
+		}
+		if len(problems) == 0 {
+			break
+		}
+
+		if attempt >= retries {
+			return fmt.Errorf("%s didn't complete its task: %s", launch.Label, strings.Join(problems, "; "))
+		}
+		fmt.Printf("Output check failed: %s.\nRetrying %s (%d/%d)...\n", strings.Join(problems, "; "), launch.Label, attempt+1, retries)
+
+		run, err = retryLaunch(launch, session, role, problems)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%s completed successfully.\n", launch.Label)
+	return nil
+}
+
+// retryLaunch returns the run retrying a launch whose output failed the checks. It continues the session
+// that just failed with only the correction, so the agent fixes its output instead of doing the task again.
+// Without a session, the whole prompt is sent again with the correction.
+func retryLaunch(launch agentLaunch, failedSession string, role config.Role, problems []string) (agentLaunch, error) {
+	prompt := launch.Prompt
+	if failedSession != "" {
+		prompt, launch.Resume = "", failedSession
+	}
+	correction, err := correctionPrompt(prompt, role, taskContext{ID: launch.Task, Path: launch.TaskFile}, problems)
+	if err != nil {
+		return agentLaunch{}, err
+	}
+	launch.Prompt = correction
+	return launch, nil
+}
+
+// runAgentOnce runs an agent with the selected backend, waits for it to finish, records its usage
+// and enforces the section ownership of its role. It returns the session of the agent, empty if it reported none
+func runAgentOnce(cmd *cobra.Command, launch agentLaunch) (string, error) {
+	backend, err := agentBackend()
+	if err != nil {
+		return "", err
+	}
+
+	snapshot := snapshotContext(launch.TaskFile)
+
+	settings := config.Current()
+	req := claude.Request{
+		Prompt:       launch.Prompt,
+		Resume:       launch.Resume,
+		Model:        settings.RoleModel(launch.Role),
+		AllowedTools: settings.RoleAllowedTools(launch.Role),
+	}
+	setAgentLimits(&req, launch.Role)
+
+	// The run can't spend more than what is left of the budget of the task. Raw runs have no task
+	var budget *taskBudget
+	if launch.Role != "raw" {
+		budget, err = loadTaskBudget(launch.Task)
+		if err != nil {
+			return "", err
+		}
+	}
+	if budget != nil {
+		if err := budget.check(); err != nil {
+			return "", err
+		}
+		budget.limit(&req)
+	}
+
+	// Ctrl+C stops the agent instead of Astropath, so the run is recorded
+	ctx, stop := proc.NotifyContext(cmd.Context())
+	defer stop()
+
+	var done <-chan claude.Result
+	if launch.Streaming {
+		done = claude.RunAgentWithStreaming(ctx, backend, req)
+	} else {
+		done = claude.RunAgent(ctx, backend, req)
+	}
+
+	// Give the goroutine a moment to start before returning
+	time.Sleep(100 * time.Millisecond)
+	if launch.Streaming {
+		fmt.Printf("%s launched with streaming. Use Ctrl+C to stop.\n", launch.Label)
+	} else {
+		fmt.Printf("%s launched. Use Ctrl+C to stop.\n", launch.Label)
+	}
+
+	// Wait for the agent to complete
+	result := <-done
+	if result.Err == nil && result.IsError {
+		result.Err = fmt.Errorf("agent reported an error result")
+	}
+	if budget != nil {
+		result.Err = budget.explain(result.Err)
+	}
+	recordUsage(cmd, launch, backend, result)
+	recordSession(launch, result)
+	if onAgentResult != nil {
+		onAgentResult(launch, result)
+	}
+
+	if role, ok := config.GetRole(launch.Role); ok {
+		if err := enforceOwnership(role, snapshot); err != nil {
+			return "", err
+		}
+	}
+
+	if result.Err != nil {
+		return result.SessionID, fmt.Errorf("%s exited with error: %w", launch.Label, result.Err)
+	}
+	return result.SessionID, nil
+}
+
+// setAgentLimits sets the time, turn, cost and token limits of an agent of the role: the flags first,
+// then the running pipeline step, then the configuration. Zero means no limit.
+func setAgentLimits(req *claude.Request, role string) {
+	settings := config.Current()
+	req.Timeout = settings.RoleTimeout(role)
+	req.MaxTurns = settings.RoleMaxTurns(role)
+	req.MaxCost = settings.RoleMaxCost(role)
+	req.MaxTokens = settings.RoleMaxTokens(role)
+	if activeStep != nil && activeStep.Role == role {
+		if activeStep.Timeout > 0 {
+			req.Timeout = time.Duration(activeStep.Timeout)
+		}
+		if activeStep.MaxTurns > 0 {
+			req.MaxTurns = activeStep.MaxTurns
+		}
+		if activeStep.MaxCost > 0 {
+			req.MaxCost = activeStep.MaxCost
+		}
+		if activeStep.MaxTokens > 0 {
+			req.MaxTokens = activeStep.MaxTokens
+		}
+	}
+	if timeoutFlag > 0 {
+		req.Timeout = timeoutFlag
+	}
+	if maxTurnsFlag > 0 {
+		req.MaxTurns = maxTurnsFlag
+	}
+	if maxCostFlag > 0 {
+		req.MaxCost = maxCostFlag
+	}
+	if maxTokensFlag > 0 {
+		req.MaxTokens = maxTokensFlag
+	}
+}
+
+// recordSession keeps the session of the run as the last one of its role for the task,
+// so it can be continued. Failing to do so only prints a warning
+func recordSession(launch agentLaunch, result claude.Result) {
+	if result.SessionID == "" {
+		return
+	}
+	session := task.Session{ID: result.SessionID, Branch: launch.Branch, Time: time.Now()}
+	if err := task.RecordSession(launch.Task, launch.Role, session); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record the agent session: %v\n", err)
+	}
+}
+
+// recordUsage appends the run to the usage ledger. Failing to do so only prints a warning
+func recordUsage(cmd *cobra.Command, launch agentLaunch, backend claude.Backend, result claude.Result) {
+	record := usage.Record{
+		Time:                time.Now().Add(-result.Duration),
+		Command:             cmd.Name(),
+		Role:                launch.Role,
+		Task:                launch.Task,
+		Branch:              launch.Branch,
+		Backend:             backend.Name(),
+		Model:               result.Model,
+		SessionID:           result.SessionID,
+		InputTokens:         result.Usage.InputTokens,
+		OutputTokens:        result.Usage.OutputTokens,
+		CacheCreationTokens: result.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     result.Usage.CacheReadInputTokens,
+		CostUSD:             result.CostUSD,
+		CostEstimated:       result.Estimated,
+		NumTurns:            result.NumTurns,
+		DurationMs:          result.Duration.Milliseconds(),
+		Status:              usage.StatusSuccess,
+	}
+	if result.Err != nil {
+		record.Status = usage.StatusError
+		var limitErr *claude.LimitError
+		if proc.Interrupted(result.Err) {
+			record.Status = usage.StatusCancelled
+		} else if errors.As(result.Err, &limitErr) {
+			record.Status = usage.StatusLimit
+		}
+		record.Error = result.Err.Error()
+	}
+
+	if err := usage.Append(config.UsageLedgerPath, record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record usage: %v\n", err)
+	}
+}
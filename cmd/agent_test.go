@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/git/gittest"
+)
+
+func TestRetryLaunch(t *testing.T) {
+	role := config.Role{Section: config.SolutionProposalSection}
+	problems := []string{"the section is empty"}
+
+	tests := []struct {
+		name          string
+		launch        agentLaunch
+		failedSession string
+		wantResume    string
+		wantOriginal  bool // The retry prompt starts with the original prompt
+	}{
+		{"continues the failed session", agentLaunch{Prompt: "Analyze the issue"}, "session-2", "session-2", false},
+		{"continues the failed session of a resumed launch", agentLaunch{Prompt: "also handle nil", Resume: "session-1"}, "session-2", "session-2", false},
+		{"sends the whole prompt without a session", agentLaunch{Prompt: "Analyze the issue"}, "", "", true},
+		{"keeps the resumed session without a new one", agentLaunch{Prompt: "also handle nil", Resume: "session-1"}, "", "session-1", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			retry, err := retryLaunch(test.launch, test.failedSession, role, problems)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if retry.Resume != test.wantResume {
+				t.Errorf("Resume = %q, want %q", retry.Resume, test.wantResume)
+			}
+			if got := strings.HasPrefix(retry.Prompt, test.launch.Prompt); got != test.wantOriginal {
+				t.Errorf("prompt %q starts with the original prompt: %v, want %v", retry.Prompt, got, test.wantOriginal)
+			}
+			if !strings.Contains(retry.Prompt, problems[0]) || !strings.Contains(retry.Prompt, role.Section) {
+				t.Errorf("prompt %q is missing the correction", retry.Prompt)
+			}
+		})
+	}
+}
+
+func TestSetAgentLimits(t *testing.T) {
+	// Reload the default settings once the test is over, from a directory without configuration files
+	t.Cleanup(func() { config.Load() })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	gittest.Chdir(t, t.TempDir())
+	project := "timeout: 30m\nmax_turns: 10\nmax_cost: 5\nroles:\n  developer:\n    max_turns: 20\n    max_tokens: 1000\n"
+	if err := os.WriteFile(config.ProjectConfigPath, []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		role  string
+		step  *config.Step
+		flags claude.Request // Limits given with the flags
+		want  claude.Request
+	}{
+		{
+			name: "configuration",
+			role: "analyst",
+			want: claude.Request{Timeout: 30 * time.Minute, MaxTurns: 10, MaxCost: 5},
+		},
+		{
+			name: "role configuration",
+			role: "developer",
+			want: claude.Request{Timeout: 30 * time.Minute, MaxTurns: 20, MaxCost: 5, MaxTokens: 1000},
+		},
+		{
+			name: "step",
+			role: "developer",
+			step: &config.Step{Role: "developer", Timeout: config.Duration(time.Minute), MaxCost: 1},
+			want: claude.Request{Timeout: time.Minute, MaxTurns: 20, MaxCost: 1, MaxTokens: 1000},
+		},
+		{
+			name: "step of another role",
+			role: "developer",
+			step: &config.Step{Role: "reviewer", MaxTurns: 3},
+			want: claude.Request{Timeout: 30 * time.Minute, MaxTurns: 20, MaxCost: 5, MaxTokens: 1000},
+		},
+		{
+			name:  "flags",
+			role:  "developer",
+			step:  &config.Step{Role: "developer", MaxTurns: 3, MaxTokens: 500},
+			flags: claude.Request{Timeout: time.Hour, MaxTurns: 50},
+			want:  claude.Request{Timeout: time.Hour, MaxTurns: 50, MaxCost: 5, MaxTokens: 500},
+		},
+	}
+
+	defer func(step *config.Step, timeout time.Duration, turns int, cost float64, tokens int64) {
+		activeStep, timeoutFlag, maxTurnsFlag, maxCostFlag, maxTokensFlag = step, timeout, turns, cost, tokens
+	}(activeStep, timeoutFlag, maxTurnsFlag, maxCostFlag, maxTokensFlag)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			activeStep = test.step
+			timeoutFlag, maxTurnsFlag = test.flags.Timeout, test.flags.MaxTurns
+			maxCostFlag, maxTokensFlag = test.flags.MaxCost, test.flags.MaxTokens
+
+			var req claude.Request
+			setAgentLimits(&req, test.role)
+			if req.Timeout != test.want.Timeout || req.MaxTurns != test.want.MaxTurns || req.MaxCost != test.want.MaxCost || req.MaxTokens != test.want.MaxTokens {
+				t.Errorf("setAgentLimits() = %v, %d turns, $%v, %d tokens, want %v, %d turns, $%v, %d tokens",
+					req.Timeout, req.MaxTurns, req.MaxCost, req.MaxTokens,
+					test.want.Timeout, test.want.MaxTurns, test.want.MaxCost, test.want.MaxTokens)
+			}
+		})
+	}
+}
@@ -2,9 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"time"
 
-	"github.com/fynardo/astropath/internal/claude"
 	"github.com/fynardo/astropath/config"
 	"github.com/spf13/cobra"
 )
@@ -22,33 +20,25 @@ var analyzeCmd = &cobra.Command{
 func claudeAnalyze(cmd *cobra.Command) error {
 	fmt.Println("Launching Astropath's Claude Analyst agent...")
 
-	prompt := config.GetPrompt(config.AnalystPromptType)
-	
-	// Check if streaming flag is set, default to false for analyze
-	useStreaming := streaming || false
-	
-	var done <-chan error
-	if useStreaming {
-		done = claude.RunAgentWithStreaming(prompt)
-	} else {
-		done = claude.RunAgent(prompt)
+	task, err := currentTask()
+	if err != nil {
+		return err
 	}
-
-	// Give the goroutine a moment to start before returning
-	time.Sleep(100 * time.Millisecond)
-	if useStreaming {
-		fmt.Println("Astropath's Claude Analyst agent launched with streaming. Use Ctrl+C to stop.")
-	} else {
-		fmt.Println("Astropath's Claude Analyst agent launched. Use Ctrl+C to stop.")
+	if err := checkRequiredSections("analyst", task); err != nil {
+		return err
 	}
-	
-	// Wait for the agent to complete
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("Claude Analyst agent exited with error: %v", err)
-		}
-		fmt.Println("Claude agent completed successfully.")
-		return nil
+
+	prompt, err := renderPrompt(config.AnalystPromptType, task.promptParams())
+	if err != nil {
+		return err
 	}
+
+	return launchAgent(cmd, agentLaunch{
+		Label:     "Astropath's Claude Analyst agent",
+		Role:      "analyst",
+		Task:      task.ID,
+		TaskFile:  task.Path,
+		Prompt:    prompt,
+		Streaming: streaming,
+	})
 }
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git"
+)
+
+// defaultBaseBranch is used when the base branch can't be detected
+const defaultBaseBranch = "main"
+
+// baseBranchFlag holds the --base flag shared by the commands that work on branches
+var baseBranchFlag string
+
+type BranchNameParams struct {
+	Role string
+	Date string
+	Time string
+}
+
+// resolveBaseBranch returns the branch agents must never work on directly and changes are compared to:
+// the --base flag, the configured base_branch, or the branch detected from origin/HEAD and the usual trunk names
+func resolveBaseBranch() string {
+	if baseBranchFlag != "" {
+		return baseBranchFlag
+	}
+	if configured := config.Current().BaseBranch; configured != "" {
+		return configured
+	}
+	if detected := git.DefaultBranch(); detected != "" {
+		return detected
+	}
+	return defaultBaseBranch
+}
+
+// resolveBranch decides which branch a role works on and checks it out:
+//   - If a branch is given, use it (creating it from the base branch when allowed and missing)
+//   - If no branch is given but the current branch is not the base branch, use the current one
+//   - If no branch is given and the current branch is the base branch, create a new one (when allowed)
+func resolveBranch(requested string, base string, role string, create bool) (string, error) {
+	current, err := git.CurrentBranch()
+	if err != nil {
+		return "", fmt.Errorf("getting current branch: %v", err)
+	}
+
+	if requested == "" {
+		if current != "" && current != base {
+			fmt.Printf("Using current branch '%s'.\n", current)
+			return current, nil
+		}
+		if !create {
+			return "", fmt.Errorf("no branch given and the current branch is the base branch '%s', specify the branch to use", base)
+		}
+		requested, err = newBranchName(role)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if requested == base {
+		return "", fmt.Errorf("agents can't work on the base branch '%s'", base)
+	}
+
+	if requested == current {
+		fmt.Printf("Using current branch '%s'.\n", current)
+		return requested, nil
+	}
+
+	if git.BranchExists(requested) {
+		if err := git.Checkout(requested); err != nil {
+			return "", fmt.Errorf("checking out branch '%s': %v", requested, err)
+		}
+		fmt.Printf("Checked out branch '%s'.\n", requested)
+		return requested, nil
+	}
+
+	if !create {
+		return "", fmt.Errorf("branch '%s' does not exist", requested)
+	}
+	startPoint, err := branchStartPoint(base)
+	if err != nil {
+		return "", err
+	}
+	if err := git.CreateBranch(requested, startPoint); err != nil {
+		return "", fmt.Errorf("creating branch '%s': %v", requested, err)
+	}
+	fmt.Printf("Created and checked out branch '%s'.\n", requested)
+	return requested, nil
+}
+
+// branchStartPoint returns where new branches start: the base branch, or its remote-tracking branch
+// when it was never checked out locally
+func branchStartPoint(base string) (string, error) {
+	if git.BranchExists(base) {
+		return base, nil
+	}
+	if git.RemoteBranchExists(base) {
+		return "origin/" + base, nil
+	}
+	return "", fmt.Errorf("the base branch '%s' doesn't exist, locally or on origin, to create the new branch from: choose another one with --base or base_branch", base)
+}
+
+// newBranchName renders the branch naming template
+func newBranchName(role string) (string, error) {
+	now := time.Now()
+	params := BranchNameParams{
+		Role: role,
+		Date: now.Format("20060102"),
+		Time: now.Format("150405"),
+	}
+
+	text := config.BranchNameTemplate
+	if configured := config.Current().BranchTemplate; configured != "" {
+		text = configured
+	}
+	templ, err := template.New("branch").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing branch name template: %v", err)
+	}
+
+	var buff = bytes.Buffer{}
+	if err := templ.Execute(&buff, params); err != nil {
+		return "", fmt.Errorf("executing branch name template: %v", err)
+	}
+
+	name := buff.String()
+	if !git.ValidBranchName(name) {
+		return "", fmt.Errorf("branch name template produced an invalid name '%s'", name)
+	}
+	return name, nil
+}
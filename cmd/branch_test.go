@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/fynardo/astropath/internal/git/gittest"
+)
+
+func TestResolveBranchStartPoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T)
+		wantStart string // Commit the new branch starts from, empty when it can't be created
+	}{
+		{
+			name:      "local base branch",
+			setup:     func(t *testing.T) {},
+			wantStart: "main",
+		},
+		{
+			name: "remote-tracking base branch",
+			setup: func(t *testing.T) {
+				// Only origin/main is left, the local main moved on and was deleted
+				gittest.Git(t, "update-ref", "refs/remotes/origin/main", "main")
+				gittest.Git(t, "checkout", "-q", "-b", "other")
+				gittest.Commit(t, "other")
+				gittest.Git(t, "branch", "-q", "-D", "main")
+			},
+			wantStart: "origin/main",
+		},
+		{
+			name: "missing base branch",
+			setup: func(t *testing.T) {
+				gittest.Git(t, "checkout", "-q", "-b", "other")
+				gittest.Git(t, "branch", "-q", "-D", "main")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gittest.NewRepo(t)
+			test.setup(t)
+			var start string
+			if test.wantStart != "" {
+				start = gittest.Git(t, "rev-parse", test.wantStart)
+			}
+
+			branch, err := resolveBranch("feature", "main", "developer", true)
+			if test.wantStart == "" {
+				if err == nil || git.BranchExists("feature") {
+					t.Fatalf("resolveBranch() = %s, %v, want an error and no branch", branch, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := gittest.Git(t, "rev-parse", "HEAD"); got != start {
+				t.Errorf("the new branch starts at %s, want %s (%s)", got, start, test.wantStart)
+			}
+			if upstream, err := exec.Command("git", "rev-parse", "--abbrev-ref", "feature@{upstream}").Output(); err == nil {
+				t.Errorf("the new branch tracks %s", upstream)
+			}
+		})
+	}
+}
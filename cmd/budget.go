@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/usage"
+)
+
+// taskMaxCostFlag and taskMaxTokensFlag hold the persistent --task-max-cost and --task-max-tokens flags
+var taskMaxCostFlag float64
+var taskMaxTokensFlag int64
+
+// taskBudget is the budget of a task and what its agent runs have spent so far, according to the usage ledger
+type taskBudget struct {
+	Task        string
+	MaxCost     float64 // USD, zero for no limit
+	MaxTokens   int64   // Zero for no limit
+	SpentCost   float64
+	SpentTokens int64
+
+	// The budget was the tightest cost or token limit of the last agent run
+	limitsCost   bool
+	limitsTokens bool
+}
+
+// budgetError is returned instead of running an agent for a task that has spent its budget
+type budgetError struct {
+	budget *taskBudget
+}
+
+func (e *budgetError) Error() string {
+	b := e.budget
+	spent := fmt.Sprintf("$%.2f", b.SpentCost)
+	if b.MaxCost > 0 {
+		spent += fmt.Sprintf(" of $%.2f", b.MaxCost)
+	}
+	tokens := fmt.Sprintf("%d tokens", b.SpentTokens)
+	if b.MaxTokens > 0 {
+		tokens = fmt.Sprintf("%d of %d tokens", b.SpentTokens, b.MaxTokens)
+	}
+	return fmt.Sprintf("the budget of %s is spent (%s, %s), raise task_budget or use --task-max-cost and --task-max-tokens to go on", b.name(), spent, tokens)
+}
+
+// loadTaskBudget returns the budget of a task, from the flags or the configuration, with its spend so far.
+// It returns nil when no budget is set.
+func loadTaskBudget(task string) (*taskBudget, error) {
+	limits := config.Current().TaskBudget
+	if taskMaxCostFlag > 0 {
+		limits.MaxCost = taskMaxCostFlag
+	}
+	if taskMaxTokensFlag > 0 {
+		limits.MaxTokens = taskMaxTokensFlag
+	}
+	if limits.MaxCost == 0 && limits.MaxTokens == 0 {
+		return nil, nil
+	}
+
+	records, err := usage.Load(config.UsageLedgerPath)
+	if err != nil {
+		return nil, err
+	}
+	budget := &taskBudget{Task: task, MaxCost: limits.MaxCost, MaxTokens: limits.MaxTokens}
+	budget.SpentCost, budget.SpentTokens = taskSpend(records, task)
+	return budget, nil
+}
+
+// taskSpend adds up the cost and tokens of the role runs of a task in the ledger.
+// Raw runs don't belong to any task, even though they are recorded without one like the default ASTROPATH.md.
+func taskSpend(records []usage.Record, task string) (float64, int64) {
+	var cost float64
+	var tokens int64
+	for _, record := range records {
+		if record.Task == task && record.Role != "raw" {
+			cost += record.CostUSD
+			tokens += record.SpentTokens()
+		}
+	}
+	return cost, tokens
+}
+
+// name returns how messages refer to the task
+func (b *taskBudget) name() string {
+	if b.Task == "" {
+		return config.AstropathFile
+	}
+	return fmt.Sprintf("task '%s'", b.Task)
+}
+
+// check returns a budgetError when nothing is left of the budget
+func (b *taskBudget) check() error {
+	if b.MaxCost > 0 && b.SpentCost >= b.MaxCost || b.MaxTokens > 0 && b.SpentTokens >= b.MaxTokens {
+		return &budgetError{budget: b}
+	}
+	return nil
+}
+
+// limit lowers the cost and token limits of the request to what is left of the budget
+func (b *taskBudget) limit(req *claude.Request) {
+	b.limitsCost, b.limitsTokens = false, false
+	if left := b.MaxCost - b.SpentCost; b.MaxCost > 0 && (req.MaxCost == 0 || left < req.MaxCost) {
+		req.MaxCost = left
+		b.limitsCost = true
+	}
+	if left := b.MaxTokens - b.SpentTokens; b.MaxTokens > 0 && (req.MaxTokens == 0 || left < req.MaxTokens) {
+		req.MaxTokens = left
+		b.limitsTokens = true
+	}
+}
+
+// explain points out when the run was stopped by what was left of the budget rather than by its own limits
+func (b *taskBudget) explain(err error) error {
+	var limitErr *claude.LimitError
+	if !errors.As(err, &limitErr) {
+		return err
+	}
+	if limitErr.MaxCost > 0 && b.limitsCost || limitErr.MaxTokens > 0 && b.limitsTokens {
+		return fmt.Errorf("%w, what was left of the budget of %s", err, b.name())
+	}
+	return err
+}
+
+// formatSpend renders a cost and a number of tokens, e.g. "$1.2345, 80000 tokens"
+func formatSpend(cost float64, tokens int64) string {
+	return fmt.Sprintf("$%.4f, %d tokens", cost, tokens)
+}
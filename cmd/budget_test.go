@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/usage"
+)
+
+func TestTaskSpend(t *testing.T) {
+	records := []usage.Record{
+		{Task: "parser", Role: "developer", CostUSD: 1, InputTokens: 100, OutputTokens: 10, CacheCreationTokens: 5, CacheReadTokens: 1000},
+		{Task: "parser", Role: "reviewer", CostUSD: 0.5, InputTokens: 50},
+		{Task: "other", Role: "developer", CostUSD: 3, InputTokens: 300},
+		{Role: "analyst", CostUSD: 0.25, InputTokens: 20},
+		{Role: "raw", CostUSD: 7, InputTokens: 700},
+	}
+	tests := []struct {
+		task       string
+		wantCost   float64
+		wantTokens int64
+	}{
+		{"parser", 1.5, 165},
+		{"other", 3, 300},
+		{"", 0.25, 20}, // Raw runs don't count against ASTROPATH.md
+		{"missing", 0, 0},
+	}
+	for _, test := range tests {
+		cost, tokens := taskSpend(records, test.task)
+		if cost != test.wantCost || tokens != test.wantTokens {
+			t.Errorf("taskSpend(%q) = %v, %d, want %v, %d", test.task, cost, tokens, test.wantCost, test.wantTokens)
+		}
+	}
+}
+
+func TestTaskBudgetCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget taskBudget
+		spent  bool
+	}{
+		{"cost left", taskBudget{MaxCost: 5, SpentCost: 4.99}, false},
+		{"cost spent", taskBudget{MaxCost: 5, SpentCost: 5}, true},
+		{"tokens spent", taskBudget{MaxTokens: 1000, SpentTokens: 1200}, true},
+		{"no cost limit", taskBudget{MaxTokens: 1000, SpentCost: 100}, false},
+	}
+	for _, test := range tests {
+		err := test.budget.check()
+		var budgetErr *budgetError
+		if spent := errors.As(err, &budgetErr); spent != test.spent {
+			t.Errorf("%s: check() = %v, want spent %v", test.name, err, test.spent)
+		}
+	}
+}
+
+func TestTaskBudgetLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		budget     taskBudget
+		req        claude.Request
+		wantCost   float64
+		wantTokens int64
+		limitsCost bool
+	}{
+		{"budget is tighter", taskBudget{MaxCost: 5, SpentCost: 4}, claude.Request{MaxCost: 2}, 1, 0, true},
+		{"run limit is tighter", taskBudget{MaxCost: 5, SpentCost: 1}, claude.Request{MaxCost: 2}, 2, 0, false},
+		{"no run limit", taskBudget{MaxCost: 5, SpentCost: 1, MaxTokens: 100, SpentTokens: 40}, claude.Request{}, 4, 60, true},
+		{"no budget", taskBudget{}, claude.Request{MaxCost: 2, MaxTokens: 10}, 2, 10, false},
+	}
+	for _, test := range tests {
+		req := test.req
+		test.budget.limit(&req)
+		if req.MaxCost != test.wantCost || req.MaxTokens != test.wantTokens || test.budget.limitsCost != test.limitsCost {
+			t.Errorf("%s: limit() = $%v, %d tokens, budget limits cost %v, want $%v, %d tokens, %v",
+				test.name, req.MaxCost, req.MaxTokens, test.budget.limitsCost, test.wantCost, test.wantTokens, test.limitsCost)
+		}
+
+		// Only the limits set by the budget are explained by it
+		err := test.budget.explain(&claude.LimitError{MaxCost: req.MaxCost})
+		if explained := err.Error() != (&claude.LimitError{MaxCost: req.MaxCost}).Error(); explained != test.limitsCost {
+			t.Errorf("%s: explain() = %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and edit the Astropath configuration",
+	Long: `Show and edit the Astropath configuration.
+
+Settings are read from the user configuration file (` + userConfigHint() + `)
+and from the project configuration file (` + config.ProjectConfigPath + `), which overrides it.
+Command line flags override both. The files are YAML, or TOML when named .astropath.toml and config.toml
+instead (having both formats for the same file is an error).
+
+The files are merged key by key, also inside roles: a project file setting roles.developer.timeout
+keeps the roles.developer.model of the user file. Empty or zero values don't override anything.
+Lists, such as claude.args or verify.commands, and pipelines with the same name are replaced whole.
+
+Keys are dotted paths, e.g. 'base_branch', 'claude.binary' or 'roles.developer.model'.
+
+Examples:
+  astropath config show
+  astropath config get roles.developer.model
+  astropath config set roles.developer.model opus
+  astropath config set claude.args '[--permission-mode, acceptEdits]'
+  astropath config set --user backend fake`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the merged configuration, or the content of one of the files",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, _ := cmd.Flags().GetBool("project")
+		user, _ := cmd.Flags().GetBool("user")
+		if project && user {
+			return fmt.Errorf("use either --project or --user")
+		}
+		return handleConfigShow(project, user)
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting from the merged configuration",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return handleConfigGet(args[0])
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting in the project configuration file (or the user one with --user)",
+	Long: `Change a setting in the project configuration file, or in the user one with --user.
+
+The value is read as YAML, so lists can be given as '[a, b]'. An empty value removes the setting.
+Comments of YAML files are kept, TOML files are rewritten without them.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		user, _ := cmd.Flags().GetBool("user")
+		return handleConfigSet(args[0], args[1], user)
+	},
+}
+
+func init() {
+	configShowCmd.Flags().Bool("project", false, "Only show the project configuration file")
+	configShowCmd.Flags().Bool("user", false, "Only show the user configuration file")
+	configSetCmd.Flags().Bool("user", false, "Change the user configuration file instead of the project one")
+
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+}
+
+// userConfigHint returns the user configuration file for help messages
+func userConfigHint() string {
+	if path := config.UserConfigPath(); path != "" {
+		return path
+	}
+	return "~/.config/astropath/config.yaml"
+}
+
+// configFile returns the configuration file edited by the config commands
+func configFile(user bool) (string, error) {
+	if !user {
+		return config.ProjectConfigFile()
+	}
+	path, err := config.UserConfigFile()
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("can't find the user configuration directory")
+	}
+	return path, nil
+}
+
+func handleConfigShow(project bool, user bool) error {
+	settings := config.Current()
+	if project || user {
+		path, err := configFile(user)
+		if err != nil {
+			return err
+		}
+		settings, err = config.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n", path)
+	}
+
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("encoding configuration: %v", err)
+	}
+	if strings.TrimSpace(string(out)) == "{}" {
+		fmt.Println("# No settings, the defaults are used")
+		return nil
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+func handleConfigGet(key string) error {
+	var root yaml.Node
+	if err := root.Encode(config.Current()); err != nil {
+		return fmt.Errorf("encoding configuration: %v", err)
+	}
+
+	node := &root
+	for _, part := range strings.Split(key, ".") {
+		node = mappingValue(node, part)
+		if node == nil {
+			return fmt.Errorf("'%s' is not set", key)
+		}
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		fmt.Println(node.Value)
+		return nil
+	}
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return fmt.Errorf("encoding '%s': %v", key, err)
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+func handleConfigSet(key string, value string, user bool) error {
+	path, err := configFile(user)
+	if err != nil {
+		return err
+	}
+
+	// Edit the file as a YAML tree so comments and the order of the keys are kept
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s: %v", path, err)
+	}
+	if config.IsTOML(path) {
+		if data, err = config.TOMLToYAML(data); err != nil {
+			return fmt.Errorf("parsing %s: %v", path, err)
+		}
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("invalid value '%s': %v", value, err)
+	}
+
+	parts := strings.Split(key, ".")
+	if len(parsed.Content) == 0 {
+		removeKey(doc.Content[0], parts)
+	} else if err := setKey(doc.Content[0], parts, parsed.Content[0]); err != nil {
+		return fmt.Errorf("setting '%s': %v", key, err)
+	}
+
+	var buff bytes.Buffer
+	encoder := yaml.NewEncoder(&buff)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("encoding %s: %v", path, err)
+	}
+	out := buff.Bytes()
+	if config.IsTOML(path) {
+		if out, err = config.YAMLToTOML(out); err != nil {
+			return fmt.Errorf("encoding %s: %v", path, err)
+		}
+	}
+	var updated config.Settings
+	if err := config.Decode(path, out, &updated); err != nil {
+		return fmt.Errorf("invalid setting '%s': %v", key, err)
+	}
+	// The file must also be valid merged with the other one, or every command would fail to load them
+	if _, err := config.MergeWith(path, &updated); err != nil {
+		return fmt.Errorf("invalid setting '%s': %v", key, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	fmt.Printf("Updated '%s' in %s.\n", key, path)
+	return nil
+}
+
+// mappingValue returns the value of a key in a YAML mapping (or document), nil when missing
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setKey sets the value at a dotted path, creating the intermediate mappings
+func setKey(node *yaml.Node, path []string, value *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("'%s' is not a mapping", path[0])
+	}
+
+	existing := mappingValue(node, path[0])
+	if len(path) == 1 {
+		if existing != nil {
+			// Keep the comments attached to the old value
+			value.HeadComment, value.LineComment, value.FootComment = existing.HeadComment, existing.LineComment, existing.FootComment
+			*existing = *value
+			return nil
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}, value)
+		return nil
+	}
+
+	if existing == nil {
+		existing = &yaml.Node{Kind: yaml.MappingNode}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}, existing)
+	}
+	return setKey(existing, path[1:], value)
+}
+
+// removeKey deletes the value at a dotted path, if present, and the mappings it leaves empty
+func removeKey(node *yaml.Node, path []string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		} else {
+			child := node.Content[i+1]
+			removeKey(child, path[1:])
+			if child.Kind == yaml.MappingNode && len(child.Content) == 0 {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			}
+		}
+		return
+	}
+}
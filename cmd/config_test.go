@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git/gittest"
+)
+
+func TestConfigSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+	}{
+		{"yaml", config.ProjectConfigPath, "# Shared settings\nbase_branch: develop\n"},
+		{"toml", ".astropath.toml", "base_branch = \"develop\"\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			gittest.Chdir(t, t.TempDir())
+			if err := os.WriteFile(test.path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := handleConfigSet("roles.developer.timeout", "10m", false); err != nil {
+				t.Fatal(err)
+			}
+			if err := handleConfigSet("roles.developer.timeout", "-10m", false); err == nil {
+				t.Error("handleConfigSet() accepted a negative timeout")
+			}
+			if err := handleConfigSet("pipelines.docs.steps", "[{role: docs}]", false); err == nil {
+				t.Error("handleConfigSet() accepted a pipeline with an unknown role")
+			}
+
+			settings, err := config.LoadFile(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if settings.BaseBranch != "develop" || settings.Roles["developer"].Timeout != config.Duration(10*time.Minute) {
+				t.Errorf("settings = %+v, want the base branch kept and the timeout set", settings)
+			}
+			if len(settings.Pipelines) > 0 {
+				t.Errorf("pipelines = %+v, want the invalid setting not written", settings.Pipelines)
+			}
+			if path, _ := config.ProjectConfigFile(); path != test.path {
+				t.Errorf("project configuration file = %s, want %s", path, test.path)
+			}
+		})
+	}
+}
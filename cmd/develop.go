@@ -1,31 +1,50 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
-	"text/template"
-	"time"
+	"strings"
 
-	"github.com/fynardo/astropath/internal/claude"
 	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/fynardo/astropath/internal/task"
 	"github.com/spf13/cobra"
 )
 
-type DeveloperParams struct {
-	BranchName string
-}
-
 // developCmd represents the develop command
 var developCmd = &cobra.Command{
 	Use:   "develop [branch]",
 	Short: "Launch a Claude agent that will write code as a developer",
 	Long: `Launch a Claude agent that will write code as a developer.
 
-If no branch name is provided, the agent will find a suitable name for it.
+Astropath checks out the branch before launching the agent:
+- If a branch name is provided, that branch is used (it is created if it doesn't exist)
+- If no branch name is provided and the current branch is not the base branch, the current branch is used
+- Otherwise a new branch is created from the base branch, named after the '` + config.BranchNameTemplate + `' template
+
+The base branch is detected from origin/HEAD (or main/master), use --base to override it.
+
+When the configuration has verification commands, Astropath runs them on the branch after the developer
+and writes their output to the '` + config.VerificationSection + `' section. Failing commands are fed back to the
+developer up to 'fix_attempts' times, then the command fails. Use --no-verify to skip them:
+
+  verify:
+    commands:
+      - go build ./...
+      - name: test
+        run: go test ./...
+    fix_attempts: 2
+    timeout: 10m
+
+Use --continue to send a follow-up message to the last developer session of the task instead of
+starting a new one, on the branch it worked on. The agent keeps the context of the conversation,
+so it doesn't need to read the repository again. The verification commands run afterwards as usual.
 
 Examples:
   astropath develop
-  astropath develop my-feature-branch`,
+  astropath develop my-feature-branch
+  astropath develop my-feature-branch --base develop
+  astropath develop --no-verify
+  astropath develop --continue "also handle the nil case"`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var branch string
@@ -36,48 +55,124 @@ Examples:
 	},
 }
 
+func init() {
+	developCmd.Flags().StringVar(&baseBranchFlag, "base", "", "Base branch new branches are created from (detected if not set)")
+	developCmd.Flags().BoolVar(&noVerify, "no-verify", false, "Don't run the verification commands of the configuration after the developer")
+	developCmd.Flags().StringVar(&continueMessage, "continue", "", "Follow-up message for the last developer session of the task, instead of starting a new one")
+}
+
+// continueMessage holds the --continue flag of the develop command
+var continueMessage string
+
 func claudeDevelop(cmd *cobra.Command, branch string) error {
-	fmt.Println("Launching Astropath's Claude Developer agent...")
+	if cmd.Flags().Changed("continue") {
+		if err := continueDeveloper(cmd, branch, continueMessage); err != nil {
+			return err
+		}
+		return verifyDeveloper(cmd)
+	}
+	return developAndVerify(cmd, branch, "")
+}
 
-	prompt := config.GetPrompt(config.DeveloperPromptType)
-	promptParams := DeveloperParams{BranchName: branch}
+// continueDeveloper resumes the last developer session of the task with a follow-up message,
+// on the branch given or else the one the session worked on
+func continueDeveloper(cmd *cobra.Command, branch string, message string) error {
+	if strings.TrimSpace(message) == "" {
+		return fmt.Errorf("no message provided for --continue")
+	}
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+	session, ok, err := task.LastSession(current.ID, "developer")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no developer session to continue for %s, run 'astropath develop' first", current.Path)
+	}
+
+	if branch == "" {
+		branch = session.Branch
+	}
+	base := resolveBaseBranch()
+	branch, err = resolveBranch(branch, base, "developer", false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Continuing developer session %s...\n", session.ID)
+
+	start, _ := git.CommitID("HEAD")
+	err = launchAgent(cmd, agentLaunch{
+		Label:     "Astropath's Claude Developer agent",
+		Role:      "developer",
+		Task:      current.ID,
+		TaskFile:  current.Path,
+		Branch:    branch,
+		Prompt:    message,
+		Resume:    session.ID,
+		Streaming: streaming,
+	})
+	tagTaskCommits(current, start, base)
+	return err
+}
+
+// runDeveloper launches the developer agent, appending the extra prompt template (if any) to its prompt
+func runDeveloper(cmd *cobra.Command, branch string, extraPrompt string) error {
+	fmt.Println("Launching Astropath's Claude Developer agent...")
 
-	templ, err := template.New("prompt").Parse(prompt)
+	// Pick the task before switching branches, so it can be bound to the new branch
+	current, err := currentTask()
 	if err != nil {
-		return fmt.Errorf("parsing prompt template: %v", err)
+		return err
+	}
+	if err := checkRequiredSections("developer", current); err != nil {
+		return err
 	}
 
-	var buff = bytes.Buffer{}
-	err = templ.Execute(&buff, promptParams)
+	base := resolveBaseBranch()
+	branch, err = resolveBranch(branch, base, "developer", true)
 	if err != nil {
-		return fmt.Errorf("executing prompt template: %v", err)
+		return err
 	}
 
-	// Check if streaming flag is set, default to true for develop
-	useStreaming := streaming || true
-	
-	var done <-chan error
-	if useStreaming {
-		done = claude.RunAgentWithStreaming(buff.String())
-	} else {
-		done = claude.RunAgent(buff.String())
+	// The branch may already be bound to a task, which takes precedence once checked out
+	if bound, err := currentTask(); err == nil && bound.ID != "" && bound.ID != current.ID {
+		current = bound
+		if err := checkRequiredSections("developer", current); err != nil {
+			return err
+		}
+	}
+	if err := bindTask(current, branch); err != nil {
+		return err
 	}
 
-	// Give the goroutine a moment to start before returning
-	time.Sleep(100 * time.Millisecond)
-	if useStreaming {
-		fmt.Println("Astropath's Claude Developer agent launched with streaming. Use Ctrl+C to stop.")
-	} else {
-		fmt.Println("Astropath's Claude Developer agent launched. Use Ctrl+C to stop.")
+	promptParams := current.promptParams()
+	promptParams.BranchName = branch
+	promptParams.BaseBranch = base
+	prompt, err := renderPrompt(config.DeveloperPromptType, promptParams)
+	if err != nil {
+		return err
 	}
-	
-	// Wait for the agent to complete
-	select {
-	case err := <-done:
+	if extraPrompt != "" {
+		extra, err := renderTemplate(extraPrompt, promptParams)
 		if err != nil {
-			return fmt.Errorf("Claude Developer agent exited with error: %v", err)
+			return err
 		}
-		fmt.Println("Claude agent completed successfully.")
-		return nil
+		prompt += "\n" + extra
 	}
+
+	start, _ := git.CommitID("HEAD")
+	err = launchAgent(cmd, agentLaunch{
+		Label:     "Astropath's Claude Developer agent",
+		Role:      "developer",
+		Task:      current.ID,
+		TaskFile:  current.Path,
+		Branch:    branch,
+		Prompt:    prompt,
+		Streaming: streaming,
+	})
+	tagTaskCommits(current, start, base)
+	return err
 }
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/fynardo/astropath/internal/git/gittest"
+	"github.com/fynardo/astropath/internal/task"
+	"github.com/spf13/cobra"
+)
+
+func TestContinueDeveloper(t *testing.T) {
+	gittest.NewRepo(t)
+	if _, err := task.Create("parser", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.SetActive("parser"); err != nil {
+		t.Fatal(err)
+	}
+	gittest.Git(t, "branch", "feature")
+
+	defer func(backend string, retry int, hook func(agentLaunch, claude.Result)) {
+		backendName, retries, onAgentResult = backend, retry, hook
+	}(backendName, retries, onAgentResult)
+	backendName, retries = "fake", 0
+	var launches []agentLaunch
+	onAgentResult = func(launch agentLaunch, result claude.Result) { launches = append(launches, launch) }
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	if err := continueDeveloper(cmd, "", "also handle the nil case"); err == nil {
+		t.Fatal("continueDeveloper() ran without a developer session to continue")
+	}
+
+	if err := task.RecordSession("parser", "developer", task.Session{ID: "s1", Branch: "feature", Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	// The fake agent doesn't write the section of the developer, so the run fails its checks: only the launch matters
+	continueDeveloper(cmd, "", "also handle the nil case")
+
+	if len(launches) != 1 {
+		t.Fatalf("%d agents launched, want 1", len(launches))
+	}
+	launch := launches[0]
+	if launch.Resume != "s1" || launch.Prompt != "also handle the nil case" || launch.Task != "parser" || launch.Branch != "feature" {
+		t.Errorf("launch = %+v, want the follow-up message for session s1 of parser on feature", launch)
+	}
+	if branch, err := git.CurrentBranch(); err != nil || branch != "feature" {
+		t.Errorf("current branch = %s (%v), want the branch of the session", branch, err)
+	}
+	// The continued conversation becomes the last session of the developer
+	if session, ok, err := task.LastSession("parser", "developer"); err != nil || !ok || session.ID != "fake-session" || session.Branch != "feature" {
+		t.Errorf("LastSession() = %+v, %v, %v, want the session of the follow-up", session, ok, err)
+	}
+}
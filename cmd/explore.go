@@ -2,9 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"time"
 
-	"github.com/fynardo/astropath/internal/claude"
 	"github.com/fynardo/astropath/config"
 	"github.com/spf13/cobra"
 )
@@ -21,33 +19,23 @@ var exploreCmd = &cobra.Command{
 
 func claudeExplore(cmd *cobra.Command) error {
 	fmt.Println("Launching Claude explorer agent...")
-	prompt := config.GetPrompt(config.ExplorerPromptType)
-	
-	// Check if streaming flag is set, default to false for explore
-	useStreaming := streaming || false
-	
-	var done <-chan error
-	if useStreaming {
-		done = claude.RunAgentWithStreaming(prompt)
-	} else {
-		done = claude.RunAgent(prompt)
-	}
-	
-	// Give the goroutine a moment to start before returning
-	time.Sleep(100 * time.Millisecond)
-	if useStreaming {
-		fmt.Println("Claude explorer agent launched with streaming. Use Ctrl+C to stop.")
-	} else {
-		fmt.Println("Claude explorer agent launched. Use Ctrl+C to stop.")
+
+	task, err := currentTask()
+	if err != nil {
+		return err
 	}
-	
-	// Wait for the agent to complete
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("Claude explorer agent exited with error: %v", err)
-		}
-		fmt.Println("Claude agent completed successfully.")
-		return nil
+
+	prompt, err := renderPrompt(config.ExplorerPromptType, task.promptParams())
+	if err != nil {
+		return err
 	}
+
+	return launchAgent(cmd, agentLaunch{
+		Label:     "Claude explorer agent",
+		Role:      "explorer",
+		Task:      task.ID,
+		TaskFile:  task.Path,
+		Prompt:    prompt,
+		Streaming: streaming,
+	})
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/fynardo/astropath/internal/markdown"
+	"github.com/fynardo/astropath/internal/proc"
+	"github.com/fynardo/astropath/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// noVerify holds the --no-verify flag of the develop and pipeline commands
+var noVerify bool
+
+// developAndVerify runs the developer, then the verification commands of the configuration on its branch.
+func developAndVerify(cmd *cobra.Command, branch string, extraPrompt string) error {
+	if err := runDeveloper(cmd, branch, extraPrompt); err != nil {
+		return err
+	}
+	return verifyDeveloper(cmd)
+}
+
+// verifyDeveloper runs the verification commands of the configuration on the branch the developer worked on.
+// Failures are fed back to the developer up to verify.fix_attempts times before giving up.
+func verifyDeveloper(cmd *cobra.Command) error {
+	settings := config.Current().Verify
+	if noVerify || len(settings.Commands) == 0 {
+		return nil
+	}
+
+	// The developer ran on its branch, which is still checked out
+	branch, err := git.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		failed, err := runVerification(cmd, branch)
+		if err != nil {
+			return err
+		}
+		if len(failed) == 0 {
+			fmt.Println("Verification passed.")
+			return nil
+		}
+		if attempt > settings.FixAttempts {
+			if settings.FixAttempts > 0 {
+				return fmt.Errorf("verification failed after %d fix attempts: %s", settings.FixAttempts, strings.Join(failed, ", "))
+			}
+			return fmt.Errorf("verification failed: %s", strings.Join(failed, ", "))
+		}
+
+		fmt.Printf("Verification failed: %s. Fix attempt %d/%d...\n", strings.Join(failed, ", "), attempt, settings.FixAttempts)
+		if err := runDeveloper(cmd, branch, config.VerifyFixPrompt); err != nil {
+			return fmt.Errorf("fix attempt %d: %w", attempt, err)
+		}
+	}
+}
+
+// runVerification runs the verification commands and writes their report to the context file.
+// It returns the names of the commands that failed.
+func runVerification(cmd *cobra.Command, branch string) ([]string, error) {
+	settings := config.Current().Verify
+	fmt.Printf("Verifying branch '%s'...\n", branch)
+
+	// Ctrl+C stops the running command instead of Astropath
+	ctx, stop := proc.NotifyContext(cmd.Context())
+	defer stop()
+
+	// Every command runs, even after a failure, so the report covers all of them
+	var results []verify.Result
+	for _, command := range settings.Commands {
+		result := verify.Run(ctx, command, time.Duration(settings.Timeout))
+		outcome := "passed"
+		if !result.Passed() {
+			outcome = "failed, " + result.Err.Error()
+		}
+		fmt.Printf("  %s: %s (%s)\n", command.Title(), outcome, result.Duration.Round(100*time.Millisecond))
+		results = append(results, result)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("verification %w", context.Cause(ctx))
+		}
+	}
+
+	current, err := currentTask()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(current.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading %s: %v", current.Path, err)
+	}
+	doc := markdown.Parse(string(content))
+	doc.Set(config.SectionName(config.VerificationSection), verify.Report(results, branch))
+	if err := os.WriteFile(current.Path, []byte(doc.String()), 0644); err != nil {
+		return nil, fmt.Errorf("writing %s: %v", current.Path, err)
+	}
+	return verify.Failed(results), nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git/gittest"
+	"github.com/fynardo/astropath/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+func TestRunVerification(t *testing.T) {
+	tests := []struct {
+		name       string
+		commands   string
+		wantFailed []string
+		wantReport []string
+	}{
+		{
+			name:       "passing",
+			commands:   "[echo built]",
+			wantReport: []string{": passed.\n", "## echo built: passed"},
+		},
+		{
+			name:       "failing",
+			commands:   "[echo built, {name: test, run: 'echo FAIL: TestParse; exit 1'}]",
+			wantFailed: []string{"test"},
+			wantReport: []string{": failed.\n", "## echo built: passed", "## test: failed, exit status 1", "FAIL: TestParse"},
+		},
+	}
+
+	// Reload the default settings once the test is over, from a directory without configuration files
+	t.Cleanup(func() { config.Load() })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gittest.NewRepo(t)
+			project := "verify:\n  commands: " + test.commands + "\n"
+			if err := os.WriteFile(config.ProjectConfigPath, []byte(project), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(config.AstropathFile, []byte("# Implemented Code\n\nDone.\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := config.Load(); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			failed, err := runVerification(cmd, "feature")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(failed, test.wantFailed) {
+				t.Errorf("runVerification() = %v, want %v", failed, test.wantFailed)
+			}
+
+			content, err := os.ReadFile(config.AstropathFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := markdown.Parse(string(content))
+			if implemented, _ := doc.Get("Implemented Code"); strings.TrimSpace(implemented) != "Done." {
+				t.Errorf("Implemented Code = %q, want it kept", implemented)
+			}
+			report, ok := doc.Get(config.SectionName(config.VerificationSection))
+			if !ok {
+				t.Fatalf("%s = %q, want a verification report", config.AstropathFile, content)
+			}
+			for _, want := range append(test.wantReport, "Verification of branch 'feature'") {
+				if !strings.Contains(report, want) {
+					t.Errorf("report = %q, want it to contain %q", report, want)
+				}
+			}
+		})
+	}
+}
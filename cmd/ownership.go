@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/markdown"
+)
+
+// What to do when a role modifies sections of the context file it doesn't own
+const (
+	OwnershipOff     = "off"     // Don't check
+	OwnershipWarn    = "warn"    // Print a warning
+	OwnershipRestore = "restore" // Put back the original content of those sections
+	OwnershipFail    = "fail"    // Leave the file as is and fail the command
+)
+
+var ownershipModes = []string{OwnershipOff, OwnershipWarn, OwnershipRestore, OwnershipFail}
+
+// ownershipMode holds the persistent --ownership flag
+var ownershipMode string
+
+// contextSnapshot is the content of the context file before a role runs
+type contextSnapshot struct {
+	path    string
+	content string
+	exists  bool
+}
+
+// snapshotContext reads the context file so changes made by a role can be checked afterwards
+func snapshotContext(path string) contextSnapshot {
+	content, err := os.ReadFile(path)
+	return contextSnapshot{path: path, content: string(content), exists: err == nil}
+}
+
+// enforceOwnership checks that the role only modified the section it owns, and applies the ownership mode
+func enforceOwnership(role config.Role, snapshot contextSnapshot) error {
+	if ownershipMode == OwnershipOff || role.Section == "" || !snapshot.exists {
+		return nil
+	}
+
+	content, err := os.ReadFile(snapshot.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s: %v", snapshot.path, err)
+	}
+	before := markdown.Parse(snapshot.content)
+	after := markdown.Parse(string(content))
+
+	var foreign []string
+	for _, title := range markdown.ChangedSections(before, after) {
+		if !strings.EqualFold(title, role.Section) {
+			if title == "" {
+				title = "(text before the first section)"
+			}
+			foreign = append(foreign, "'"+title+"'")
+		}
+	}
+	if os.IsNotExist(err) {
+		foreign = []string{"(the whole file was deleted)"}
+	}
+	if len(foreign) == 0 {
+		return nil
+	}
+
+	summary := fmt.Sprintf("the %s role modified sections of %s it doesn't own: %s", role.Name, snapshot.path, strings.Join(foreign, ", "))
+	switch ownershipMode {
+	case OwnershipFail:
+		return fmt.Errorf("%s", summary)
+	case OwnershipRestore:
+		// Start from the original content and only keep the changes to the owned section
+		if owned := after.TopSection(role.Section); owned != nil {
+			before.ReplaceSection(owned)
+		}
+		if err := os.WriteFile(snapshot.path, []byte(before.String()), 0644); err != nil {
+			return fmt.Errorf("restoring %s: %v", snapshot.path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s. Those sections were restored.\n", summary)
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: %s.\n", summary)
+	}
+	return nil
+}
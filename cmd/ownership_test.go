@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fynardo/astropath/config"
+)
+
+func TestEnforceOwnership(t *testing.T) {
+	const before = "# Solution Proposal\n\nplan\n\n# Implemented Code\n\n"
+	const after = "# Solution Proposal\n\nrewritten\n\n# Implemented Code\n\ncode\n\n"
+	developer := config.Role{Name: "developer", Section: "Implemented Code"}
+
+	tests := []struct {
+		name    string
+		mode    string
+		role    config.Role
+		after   string
+		wantErr bool
+		want    string
+	}{
+		{"own section", OwnershipFail, developer, "# Solution Proposal\n\nplan\n\n# Implemented Code\n\ncode\n\n", false, ""},
+		{"off", OwnershipOff, developer, after, false, after},
+		{"warn", OwnershipWarn, developer, after, false, after},
+		{"fail", OwnershipFail, developer, after, true, after},
+		{"restore", OwnershipRestore, developer, after, false, "# Solution Proposal\n\nplan\n\n# Implemented Code\n\ncode\n\n"},
+		{"restore without own section", OwnershipRestore, developer, "# Solution Proposal\n\nrewritten\n\n", false, before},
+		{"role without section", OwnershipFail, config.Role{Name: "raw"}, after, false, after},
+	}
+
+	defer func(mode string) { ownershipMode = mode }(ownershipMode)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ASTROPATH.md")
+			if err := os.WriteFile(path, []byte(before), 0644); err != nil {
+				t.Fatal(err)
+			}
+			snapshot := snapshotContext(path)
+			if err := os.WriteFile(path, []byte(test.after), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ownershipMode = test.mode
+			err := enforceOwnership(test.role, snapshot)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("enforceOwnership() = %v, want error %v", err, test.wantErr)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want != "" && string(content) != test.want {
+				t.Errorf("content = %q, want %q", content, test.want)
+			}
+		})
+	}
+}
+
+func TestEnforceOwnershipDeletedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ASTROPATH.md")
+	if err := os.WriteFile(path, []byte("# Implemented Code\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := snapshotContext(path)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(mode string) { ownershipMode = mode }(ownershipMode)
+	ownershipMode = OwnershipFail
+	if err := enforceOwnership(config.Role{Name: "developer", Section: "Implemented Code"}, snapshot); err == nil {
+		t.Error("enforceOwnership() accepted the deleted file")
+	}
+}
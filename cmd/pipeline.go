@@ -6,11 +6,25 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"text/tabwriter"
 
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/markdown"
+	"github.com/fynardo/astropath/internal/pipeline"
+	"github.com/fynardo/astropath/internal/proc"
+	"github.com/fynardo/astropath/internal/review"
 	"github.com/spf13/cobra"
 )
 
 var noPause bool
+var withTests bool
+var withRoles []string
+var pipelineName string
+var resumePipeline bool
+var fromStep string
+var onlyStep string
+var fixLoop int
 
 // pipelineCmd represents the pipeline command
 var pipelineCmd = &cobra.Command{
@@ -23,18 +37,84 @@ The pipeline executes three steps in sequence:
 2. Develop - Implements the proposed solution
 3. Review - Reviews the implementation
 
+Use the --with-tests flag to run a Test step between Develop and Review, that writes
+and runs tests for the implementation. Use --with to run other roles, such as custom roles
+from the configuration, as extra steps before Review (see 'astropath run').
+
+Other pipelines can be defined in ` + config.ProjectConfigPath + ` and run with --name, for example:
+
+  pipelines:
+    bugfix:
+      description: Analyze, develop, test and review a bug fix
+      steps:
+        - role: analyst
+        - role: developer
+          branch: create          # check out the pipeline branch first, creating it if needed
+        - role: tester
+          on_failure: continue    # stop (default), continue or ask
+          timeout: 15m            # limits of the step's agent, over the role's settings
+          max_turns: 40
+          max_cost: 2.5           # in USD, also max_tokens
+        - role: reviewer
+    docs:
+      steps:
+        - role: explorer
+          when: {section_empty: Exploration Report}
+        - role: docs-writer
+          pause: false
+
 By default, the pipeline runs in interactive mode, pausing after each step for user confirmation.
 Use the --no-pause flag to run all steps without interruption.
 
-If no branch is provided, the develop and review steps will use appropriate defaults.
+The progress of the run is saved in ` + config.PipelineStateDir + `/<task-id>.json. When a step fails or
+the pipeline is stopped at a pause, use --resume to continue from the first incomplete step, or
+--from <step> and --only <step> to run part of the last run again. --status shows its progress.
+
+Use --fix-loop <n> to iterate when the review reports major issues: the developer fixes them and the
+branch is reviewed again, until no major issues are left or after n iterations. Named pipelines set
+'fix_iterations' on their reviewer steps instead.
+
+Use --fail-on <severity> to fail the review step when a finding of the review is at or above that
+severity (major, minor or suggestion), after the fix iterations. Named pipelines set 'fail_on' instead.
+
+When the configuration has verification commands (see 'astropath develop'), they run after every
+developer step. A failing verification fails the step once the developer runs out of fix attempts.
+
+A step whose agent reaches its time, turn, cost or token limit fails, and Astropath exits with status 124.
+The cost and tokens spent by each step are printed after it and shown by --status. With a task budget
+(task_budget in the configuration, or --task-max-cost and --task-max-tokens), the agents of all the steps
+share it: each one is stopped when the budget is spent, and no step starts once nothing is left.
+
+If no branch is provided, the current branch is used unless it is the base branch, in which case
+a new branch is created before the develop step. The same branch is then tested and reviewed.
+The base branch is detected from origin/HEAD (or main/master), use --base to override it.
 
 Examples:
   astropath pipeline                    (interactive mode)
   astropath pipeline --no-pause        (non-interactive mode)
   astropath pipeline my-branch         (interactive with branch)
-  astropath pipeline my-branch --no-pause  (non-interactive with branch)`,
+  astropath pipeline my-branch --no-pause  (non-interactive with branch)
+  astropath pipeline --with-tests      (analyze -> develop -> test -> review)
+  astropath pipeline --with security-auditor  (analyze -> develop -> security-auditor -> review)
+  astropath pipeline --base develop    (branch off and compare to 'develop')
+  astropath pipeline --name bugfix     (run the 'bugfix' pipeline of the configuration)
+  astropath pipeline --list            (list the pipelines of the configuration)
+  astropath pipeline --resume          (continue the last run from its first incomplete step)
+  astropath pipeline --from review     (run the last run again from the review step)
+  astropath pipeline --only develop    (run only the develop step of the last run)
+  astropath pipeline --fix-loop 3      (develop and review again while major issues are reported, 3 times at most)
+  astropath pipeline --fail-on major --no-pause  (fail when the review reports major issues)`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			return handlePipelineList()
+		}
+		if status, _ := cmd.Flags().GetBool("status"); status {
+			return handlePipelineStatus()
+		}
+		if fromStep != "" && onlyStep != "" {
+			return fmt.Errorf("use either --from or --only")
+		}
 		var branch string
 		if len(args) > 0 {
 			branch = args[0]
@@ -45,15 +125,32 @@ Examples:
 
 func init() {
 	pipelineCmd.Flags().BoolVar(&noPause, "no-pause", false, "Skip user confirmation prompts between pipeline steps")
+	pipelineCmd.Flags().StringVar(&baseBranchFlag, "base", "", "Base branch new branches are created from and compared to (detected if not set)")
+	pipelineCmd.Flags().BoolVar(&withTests, "with-tests", false, "Run a Test step between Develop and Review")
+	pipelineCmd.Flags().StringSliceVar(&withRoles, "with", nil, "Roles to run as extra steps before Review, in order")
+	pipelineCmd.Flags().StringVar(&pipelineName, "name", "", "Run a pipeline defined in the configuration instead of the default one")
+	pipelineCmd.Flags().Bool("list", false, "List the pipelines defined in the configuration")
+	pipelineCmd.Flags().BoolVar(&resumePipeline, "resume", false, "Resume the last pipeline run from its first incomplete step")
+	pipelineCmd.Flags().StringVar(&fromStep, "from", "", "Run the pipeline from this step on")
+	pipelineCmd.Flags().StringVar(&onlyStep, "only", "", "Run only this step of the pipeline")
+	pipelineCmd.Flags().Bool("status", false, "Show the progress of the last pipeline run")
+	pipelineCmd.Flags().BoolVar(&noVerify, "no-verify", false, "Don't run the verification commands of the configuration after the developer")
+	pipelineCmd.Flags().StringVar(&failOn, "fail-on", "", "Fail the review step when a finding is at or above this severity: "+strings.Join(review.Severities(), ", "))
+	pipelineCmd.Flags().IntVar(&fixLoop, "fix-loop", 0, "Fix iterations after a review with major issues: run the developer on the review and review again, up to this number of times")
 }
 
 // waitForUserInput prompts the user to continue after completing a step
 // Returns true if user wants to continue, false if they want to abort
 func waitForUserInput(stepName string) bool {
+	return askToContinue(fmt.Sprintf("Step %s finished. Continue?", stepName))
+}
+
+// askToContinue asks a yes/no question, Enter meaning yes
+func askToContinue(question string) bool {
 	reader := bufio.NewReader(os.Stdin)
 	
 	for {
-		fmt.Printf("Step %s finished. Continue? (Y/n): ", stepName)
+		fmt.Printf("%s (Y/n): ", question)
 		
 		input, err := reader.ReadString('\n')
 		if err != nil {
@@ -80,45 +177,438 @@ func waitForUserInput(stepName string) bool {
 }
 
 func claudePipeline(cmd *cobra.Command, branch string) error {
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+	saved, err := pipeline.Load(current.ID)
+	if err != nil {
+		return err
+	}
+
+	var state *pipeline.State
+	if resumePipeline {
+		if saved == nil {
+			return fmt.Errorf("no pipeline run to resume, start one with 'astropath pipeline'")
+		}
+		if pipelineName != "" && pipelineName != saved.Pipeline {
+			return fmt.Errorf("the last pipeline run is '%s', not '%s'", saved.Pipeline, pipelineName)
+		}
+		if withTests || len(withRoles) > 0 {
+			return fmt.Errorf("--with-tests and --with can't change the steps of a resumed pipeline run")
+		}
+		state = saved
+	} else {
+		definition, err := selectPipeline()
+		if err != nil {
+			return err
+		}
+		name := pipelineName
+		if name == "" {
+			name = pipeline.DefaultName
+		}
+
+		// --from and --only re-run steps of the last run of the same pipeline, on its branch
+		if (fromStep != "" || onlyStep != "") && saved != nil && saved.Pipeline == name {
+			state = saved
+		} else {
+			state = pipeline.New(name, definition, current.ID, branch)
+		}
+	}
+	if branch != "" {
+		state.Branch = branch
+	}
+	if cmd.Flags().Changed("fix-loop") {
+		if fixLoop < 0 {
+			return fmt.Errorf("invalid --fix-loop value %d, it can't be negative", fixLoop)
+		}
+		for i := range state.Definition.Steps {
+			if state.Definition.Steps[i].Role == "reviewer" {
+				state.Definition.Steps[i].FixIterations = fixLoop
+			}
+		}
+	}
+	if cmd.Flags().Changed("fail-on") {
+		if err := validateFailOn(failOn); err != nil {
+			return err
+		}
+		for i := range state.Definition.Steps {
+			if state.Definition.Steps[i].Role == "reviewer" {
+				state.Definition.Steps[i].FailOn = failOn
+			}
+		}
+	}
+
+	first, last, err := pipelineRange(state)
+	if err != nil {
+		return err
+	}
+	if first == len(state.Steps) {
+		fmt.Println("The last pipeline run is complete, nothing to resume.")
+		return nil
+	}
+
 	fmt.Println("Launching Astropath's Pipeline of agents...")
+	return runPipeline(cmd, state, first, last)
+}
 
-	// Step 1: Analyze
-	fmt.Println("Pipeline - Step #1. Analyze...")
-	if err := claudeAnalyze(cmd); err != nil {
-		return fmt.Errorf("pipeline step 1 (analyze) failed: %v", err)
+// pipelineRange returns the steps to run, from first up to last (excluded), according to --resume, --from and --only
+func pipelineRange(state *pipeline.State) (int, int, error) {
+	name := fromStep
+	if onlyStep != "" {
+		name = onlyStep
+	}
+	if name == "" {
+		if resumePipeline {
+			return state.FirstIncomplete(), len(state.Steps), nil
+		}
+		return 0, len(state.Steps), nil
 	}
 
-	// Pause after Analyze step (unless --no-pause flag is set)
-	if !noPause {
-		if !waitForUserInput("Analyze") {
-			fmt.Println("Pipeline aborted by user.")
-			return nil
+	i := state.StepIndex(name)
+	if i < 0 {
+		var names []string
+		for _, step := range state.Steps {
+			names = append(names, step.Name)
 		}
+		return 0, 0, fmt.Errorf("unknown step '%s' (available: %s)", name, strings.Join(names, ", "))
 	}
+	if onlyStep != "" {
+		return i, i + 1, nil
+	}
+	return i, len(state.Steps), nil
+}
 
-	// Step 2: Develop
-	fmt.Println("Pipeline - Step #2. Develop...")
-	if err := claudeDevelop(cmd, branch); err != nil {
-		return fmt.Errorf("pipeline step 2 (develop) failed: %v", err)
+// selectPipeline returns the pipeline chosen with --name, or the default one built from the flags
+func selectPipeline() (config.Pipeline, error) {
+	if pipelineName == "" {
+		// Check the extra steps before running anything
+		for _, name := range withRoles {
+			if _, ok := config.GetRole(name); !ok {
+				return config.Pipeline{}, fmt.Errorf("unknown role '%s' in --with, see 'astropath run' for the available roles", name)
+			}
+		}
+		return defaultPipeline()
 	}
 
-	// Pause after Develop step (unless --no-pause flag is set)
-	if !noPause {
-		if !waitForUserInput("Develop") {
-			fmt.Println("Pipeline aborted by user.")
-			return nil
+	if withTests || len(withRoles) > 0 {
+		return config.Pipeline{}, fmt.Errorf("--with-tests and --with only apply to the default pipeline, add the steps to the '%s' pipeline instead", pipelineName)
+	}
+	pipeline, ok := config.GetPipeline(pipelineName)
+	if !ok {
+		return config.Pipeline{}, fmt.Errorf("unknown pipeline '%s' (available: %v)", pipelineName, config.PipelineNames())
+	}
+	return pipeline, nil
+}
+
+// defaultPipeline runs analyze -> develop -> [test] -> [--with roles] -> review
+func defaultPipeline() (config.Pipeline, error) {
+	steps := []config.Step{
+		{Name: "analyze", Role: "analyst"},
+		{Name: "develop", Role: "developer", Branch: config.StepBranchCreate},
+	}
+	if withTests {
+		steps = append(steps, config.Step{Name: "test", Role: "tester"})
+	}
+	for _, name := range withRoles {
+		steps = append(steps, config.Step{Role: name})
+	}
+	steps = append(steps, config.Step{Name: "review", Role: "reviewer"})
+
+	// Steps are found by name to resume the run, a role given twice or named after a step would be ambiguous
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if seen[step.Title()] {
+			return config.Pipeline{}, fmt.Errorf("duplicated step '%s' in --with, the default pipeline already has a step with that name", step.Title())
 		}
+		seen[step.Title()] = true
 	}
+	return config.Pipeline{Description: "Analyze, develop and review a task", Steps: steps}, nil
+}
 
-	// Step 3: Review
-	fmt.Println("Pipeline - Step #3. Review...")
-	if branch == "" {
-		branch = "main" // Default for review step
+// runPipeline runs the steps in order, pausing between them unless --no-pause is set,
+// and saves the progress after each one so the run can be resumed
+func runPipeline(cmd *cobra.Command, state *pipeline.State, first int, last int) error {
+	// Record the agent sessions and the spend of the running step
+	running := -1
+	onAgentResult = func(launch agentLaunch, result claude.Result) {
+		if running < 0 {
+			return
+		}
+		if result.SessionID != "" {
+			state.Steps[running].Sessions = append(state.Steps[running].Sessions, result.SessionID)
+		}
+		state.Steps[running].CostUSD += result.CostUSD
+		state.Steps[running].Tokens += result.Usage.Spent()
 	}
-	if err := claudeReview(cmd, branch); err != nil {
-		return fmt.Errorf("pipeline step 3 (review) failed: %v", err)
+	defer func() { onAgentResult = nil }()
+
+	var failed []string
+	for i := first; i < last; i++ {
+		step := state.Definition.Steps[i]
+		number := i + 1
+		title := stepTitle(step)
+
+		run, reason, err := stepShouldRun(step)
+		if err != nil {
+			return fmt.Errorf("pipeline step %d (%s) failed: %v", number, step.Title(), err)
+		}
+		if !run {
+			fmt.Printf("Pipeline - Step #%d. %s skipped: %s.\n", number, title, reason)
+			state.Finish(i, pipeline.StatusSkipped, nil)
+			saveState(state)
+			continue
+		}
+
+		fmt.Printf("Pipeline - Step #%d. %s...\n", number, title)
+		running = i
+		activeStep = &step
+		state.Start(i)
+		saveState(state)
+		err = runStep(cmd, step, &state.Branch)
+		if err == nil && step.FixIterations > 0 {
+			err = reviewFixLoop(cmd, state, i, step)
+		}
+		if err == nil && step.Role == "reviewer" {
+			err = checkFindings(step.FailOn)
+		}
+		running = -1
+		activeStep = nil
+		fmt.Printf("Pipeline - Step #%d. %s spent %s.\n", number, title, formatSpend(state.Steps[i].CostUSD, state.Steps[i].Tokens))
+
+		if err != nil && proc.Interrupted(err) {
+			// Stopped with Ctrl+C, the step runs again on resume
+			state.Finish(i, pipeline.StatusCancelled, err)
+			saveState(state)
+			fmt.Println("Pipeline cancelled. Resume it with 'astropath pipeline --resume'.")
+			return fmt.Errorf("pipeline step %d (%s) stopped: %w", number, step.Title(), err)
+		}
+		if err != nil {
+			state.Finish(i, pipeline.StatusFailed, err)
+			saveState(state)
+
+			failure := fmt.Errorf("pipeline step %d (%s) failed: %w", number, step.Title(), err)
+			switch step.OnFailure {
+			case config.OnFailureContinue:
+				fmt.Fprintf(os.Stderr, "Warning: %v. Continuing.\n", failure)
+				failed = append(failed, step.Title())
+				continue
+			case config.OnFailureAsk:
+				fmt.Fprintf(os.Stderr, "Warning: %v.\n", failure)
+				if !noPause && waitForUserInput(title) {
+					failed = append(failed, step.Title())
+					continue
+				}
+			}
+			fmt.Println("Fix the problem and resume the pipeline with 'astropath pipeline --resume'.")
+			return failure
+		}
+		state.Finish(i, pipeline.StatusSuccess, nil)
+		saveState(state)
+
+		// Pause after the step (unless --no-pause flag is set or this is the last one)
+		if !noPause && step.Pauses() && i < last-1 {
+			if !waitForUserInput(title) {
+				fmt.Println("Pipeline aborted by user. Resume it with 'astropath pipeline --resume'.")
+				return nil
+			}
+		}
 	}
 
+	fmt.Printf("Pipeline spent %s.\n", formatSpend(state.Spent()))
+	if len(failed) > 0 {
+		fmt.Printf("Pipeline completed with failed steps: %s.\n", strings.Join(failed, ", "))
+		return nil
+	}
 	fmt.Println("Pipeline completed successfully!")
 	return nil
 }
+
+// reviewFixLoop runs the developer on the major issues of the review and reviews again,
+// until the reviewer reports no major issues or the step runs out of fix iterations
+func reviewFixLoop(cmd *cobra.Command, state *pipeline.State, i int, step config.Step) error {
+	for iteration := 1; ; iteration++ {
+		current, err := currentTask()
+		if err != nil {
+			return err
+		}
+		issues, err := majorIssues(current.Path)
+		if err != nil {
+			return err
+		}
+		if len(issues) == 0 {
+			fmt.Println("The review reports no major issues.")
+			return nil
+		}
+		if iteration > step.FixIterations {
+			fmt.Fprintf(os.Stderr, "Warning: the review still reports %d major issues after %d fix iterations.\n", len(issues), step.FixIterations)
+			return nil
+		}
+
+		fmt.Printf("The review reports %d major issues:\n", len(issues))
+		for _, issue := range issues {
+			fmt.Printf("  - %s\n", truncateLine(issue, 120))
+		}
+		if !noPause && !askToContinue(fmt.Sprintf("Run fix iteration %d/%d?", iteration, step.FixIterations)) {
+			fmt.Println("Fix loop stopped by user.")
+			return nil
+		}
+
+		fmt.Printf("Pipeline - Fix iteration %d/%d. Develop...\n", iteration, step.FixIterations)
+		if err := developAndVerify(cmd, state.Branch, config.ReviewFixPrompt); err != nil {
+			return fmt.Errorf("fix iteration %d (develop): %w", iteration, err)
+		}
+		fmt.Printf("Pipeline - Fix iteration %d/%d. Review...\n", iteration, step.FixIterations)
+		if err := runStep(cmd, step, &state.Branch); err != nil {
+			return fmt.Errorf("fix iteration %d (review): %w", iteration, err)
+		}
+		state.Steps[i].Iterations = iteration
+		saveState(state)
+	}
+}
+
+// truncateLine shortens a text to a single line of at most max runes
+func truncateLine(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max-3]) + "..."
+	}
+	return text
+}
+
+// saveState writes the progress of the pipeline. Failing to do so only prints a warning
+func saveState(state *pipeline.State) {
+	if err := state.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save the pipeline state: %v\n", err)
+	}
+}
+
+// runStep runs the role of a step, keeping track of the branch the pipeline works on
+func runStep(cmd *cobra.Command, step config.Step, branch *string) error {
+	switch step.Branch {
+	case config.StepBranchCreate:
+		// Check the role can run before creating the branch, not to leave the user on a new empty one
+		current, err := currentTask()
+		if err != nil {
+			return err
+		}
+		if err := checkRequiredSections(step.Role, current); err != nil {
+			return err
+		}
+		resolved, err := resolveBranch(*branch, resolveBaseBranch(), step.Role, true)
+		if err != nil {
+			return err
+		}
+		*branch = resolved
+		return runRole(cmd, step.Role, *branch)
+	case config.StepBranchNone:
+		return runRole(cmd, step.Role, "")
+	default:
+		return runRole(cmd, step.Role, *branch)
+	}
+}
+
+// stepShouldRun evaluates the condition of a step, and returns why it is skipped otherwise
+func stepShouldRun(step config.Step) (bool, string, error) {
+	when := step.When
+	if when == nil {
+		return true, "", nil
+	}
+
+	if when.Var != "" {
+		if value := promptVars[when.Var]; value == "" || value == "false" {
+			return false, fmt.Sprintf("--var %s is not set", when.Var), nil
+		}
+	}
+	if when.SectionFilled == "" && when.SectionEmpty == "" {
+		return true, "", nil
+	}
+
+	current, err := currentTask()
+	if err != nil {
+		return false, "", err
+	}
+	content, err := os.ReadFile(current.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, "", fmt.Errorf("reading %s: %v", current.Path, err)
+	}
+	doc := markdown.Parse(string(content))
+	template := markdown.Parse(config.BaseTemplate())
+	filled := func(title string) bool {
+		title = config.SectionName(title)
+		section := doc.TopSection(title)
+		return section != nil && !isPlaceholder(section, template.TopSection(title))
+	}
+
+	if when.SectionFilled != "" && !filled(when.SectionFilled) {
+		return false, fmt.Sprintf("the '%s' section is empty", config.SectionName(when.SectionFilled)), nil
+	}
+	if when.SectionEmpty != "" && filled(when.SectionEmpty) {
+		return false, fmt.Sprintf("the '%s' section is already filled in", config.SectionName(when.SectionEmpty)), nil
+	}
+	return true, "", nil
+}
+
+// stepTitle capitalizes the name of a step for messages, e.g. "Analyze"
+func stepTitle(step config.Step) string {
+	title := step.Title()
+	return strings.ToUpper(title[:1]) + title[1:]
+}
+
+func handlePipelineStatus() error {
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+	state, err := pipeline.Load(current.ID)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		fmt.Println("No pipeline run yet.")
+		return nil
+	}
+
+	fmt.Printf("Pipeline '%s'", state.Pipeline)
+	if state.Branch != "" {
+		fmt.Printf(" on branch '%s'", state.Branch)
+	}
+	fmt.Printf(", started %s, updated %s\n", state.StartedAt.Format("2006-01-02 15:04"), state.UpdatedAt.Format("2006-01-02 15:04"))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, step := range state.Steps {
+		finished := ""
+		if !step.FinishedAt.IsZero() {
+			finished = step.FinishedAt.Format("2006-01-02 15:04")
+		}
+		status := step.Status
+		if step.Iterations > 0 {
+			status += fmt.Sprintf(" (%d fix iterations)", step.Iterations)
+		}
+		spent := ""
+		if step.CostUSD > 0 || step.Tokens > 0 {
+			spent = formatSpend(step.CostUSD, step.Tokens)
+		}
+		fmt.Fprintf(w, "%d. %s\t%s\t%s\t%s\t%s\t%s\n", i+1, step.Name, status, finished, spent, strings.Join(step.Sessions, ","), step.Error)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Spent %s.\n", formatSpend(state.Spent()))
+	return nil
+}
+
+func handlePipelineList() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range config.PipelineNames() {
+		pipeline, _ := config.GetPipeline(name)
+		var steps []string
+		for _, step := range pipeline.Steps {
+			steps = append(steps, step.Title())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, strings.Join(steps, " -> "), pipeline.Description)
+	}
+	if len(config.PipelineNames()) == 0 {
+		fmt.Fprintf(w, "No pipelines in %s, 'astropath pipeline' runs the default one.\n", config.ProjectConfigPath)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/fynardo/astropath/internal/git/gittest"
+	"github.com/fynardo/astropath/internal/pipeline"
+)
+
+func TestRunStepChecksSectionsBeforeCreatingBranch(t *testing.T) {
+	gittest.NewRepo(t)
+	if err := os.WriteFile(config.AstropathFile, []byte(config.BaseTemplate()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	branch := ""
+	step := config.Step{Role: "developer", Branch: config.StepBranchCreate}
+	if err := runStep(nil, step, &branch); err == nil {
+		t.Fatal("runStep() ran the developer without a solution proposal")
+	}
+	if branch != "" {
+		t.Errorf("the pipeline branch is '%s', want none", branch)
+	}
+	if current, err := git.CurrentBranch(); err != nil || current != "main" {
+		t.Errorf("current branch = '%s' (%v), want main", current, err)
+	}
+}
+
+func TestPipelineRange(t *testing.T) {
+	state := pipeline.New(pipeline.DefaultName, config.Pipeline{Steps: []config.Step{
+		{Role: "analyst"}, {Role: "developer"}, {Role: "tester"}, {Role: "reviewer"},
+	}}, "", "")
+	state.Steps[0].Status = pipeline.StatusSuccess
+	state.Steps[1].Status = pipeline.StatusSuccess
+	state.Steps[2].Status = pipeline.StatusFailed
+
+	tests := []struct {
+		name      string
+		resume    bool
+		from      string
+		only      string
+		wantFirst int
+		wantLast  int
+		wantErr   bool
+	}{
+		{name: "whole run", wantFirst: 0, wantLast: 4},
+		{name: "resume", resume: true, wantFirst: 2, wantLast: 4},
+		{name: "from", from: "developer", wantFirst: 1, wantLast: 4},
+		{name: "only", only: "developer", wantFirst: 1, wantLast: 2},
+		{name: "only over from", from: "analyst", only: "reviewer", wantFirst: 3, wantLast: 4},
+		{name: "from over resume", resume: true, from: "analyst", wantFirst: 0, wantLast: 4},
+		{name: "unknown step", from: "deploy", wantErr: true},
+	}
+
+	defer func(resume bool, from, only string) {
+		resumePipeline, fromStep, onlyStep = resume, from, only
+	}(resumePipeline, fromStep, onlyStep)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resumePipeline, fromStep, onlyStep = test.resume, test.from, test.only
+			first, last, err := pipelineRange(state)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("pipelineRange() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && (first != test.wantFirst || last != test.wantLast) {
+				t.Errorf("pipelineRange() = %d, %d, want %d, %d", first, last, test.wantFirst, test.wantLast)
+			}
+		})
+	}
+}
+
+func TestDefaultPipelineWith(t *testing.T) {
+	tests := []struct {
+		name      string
+		with      []string
+		wantSteps []string
+		wantErr   bool
+	}{
+		{name: "no extra steps", wantSteps: []string{"analyze", "develop", "review"}},
+		{name: "extra steps", with: []string{"explorer", "tester"}, wantSteps: []string{"analyze", "develop", "explorer", "tester", "review"}},
+		{name: "role given twice", with: []string{"explorer", "explorer"}, wantErr: true},
+	}
+
+	defer func(with []string) { withRoles = with }(withRoles)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withRoles = test.with
+			definition, err := defaultPipeline()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("defaultPipeline() error = %v, want error %v", err, test.wantErr)
+			}
+			var names []string
+			for _, step := range definition.Steps {
+				names = append(names, step.Title())
+			}
+			if !test.wantErr && !reflect.DeepEqual(names, test.wantSteps) {
+				t.Errorf("steps = %v, want %v", names, test.wantSteps)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/markdown"
+)
+
+var htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// checkRequiredSections refuses to launch a role when the sections it needs are missing,
+// empty, or still contain the template placeholders
+func checkRequiredSections(roleName string, current taskContext) error {
+	role, ok := config.GetRole(roleName)
+	if !ok || len(role.Requires) == 0 {
+		return nil
+	}
+
+	content, err := os.ReadFile(current.Path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s does not exist. Run 'astropath init' first", current.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", current.Path, err)
+	}
+
+	doc := markdown.Parse(string(content))
+	template := markdown.Parse(config.BaseTemplate())
+
+	var missing []string
+	for _, title := range role.Requires {
+		section := doc.TopSection(title)
+		if section == nil || isPlaceholder(section, template.TopSection(title)) {
+			missing = append(missing, "'"+title+"'")
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("can't launch the %s role: %s of %s must be filled in first (missing, empty or still a template placeholder)",
+		role.Name, strings.Join(missing, ", "), current.Path)
+}
+
+// isPlaceholder reports whether a section has no real content: only blank lines and HTML comments,
+// or the same text as the template
+func isPlaceholder(section *markdown.Section, template *markdown.Section) bool {
+	content := strings.TrimSpace(htmlCommentRe.ReplaceAllString(section.Content(), ""))
+	if content == "" {
+		return true
+	}
+	return template != nil && content == strings.TrimSpace(htmlCommentRe.ReplaceAllString(template.Content(), ""))
+}
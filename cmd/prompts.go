@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// varFlags holds the persistent --var flags, promptVars the parsed key=value pairs
+var varFlags []string
+var promptVars map[string]string
+
+// parsePromptVars reads the --var flags
+func parsePromptVars() error {
+	promptVars = map[string]string{}
+	for _, pair := range varFlags {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid --var value '%s', expected key=value", pair)
+		}
+		promptVars[strings.TrimSpace(key)] = value
+	}
+	return nil
+}
+
+// repoName returns the name of the repository directory, or of the current directory outside git
+func repoName() string {
+	if root, err := git.RepoRoot(); err == nil {
+		return filepath.Base(root)
+	}
+	if dir, err := os.Getwd(); err == nil {
+		return filepath.Base(dir)
+	}
+	return ""
+}
+
+// promptsCmd represents the prompts command
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "List, show and customize the prompts of the roles",
+	Long: `List, show and customize the prompts of the roles.
+
+A role uses ` + config.PromptsDir + `/<role>.md instead of its built-in prompt when that file exists.
+Use 'astropath prompts eject' to copy the built-in prompts there and edit them.
+
+Prompts are Go text/template templates. Available parameters:
+  {{ .TaskID }}        Id of the task, empty for the default ASTROPATH.md
+  {{ .TaskFile }}      Context file the agent works on, e.g. ./ASTROPATH.md
+  {{ .BranchName }}    Branch the agent works on
+  {{ .BaseBranch }}    Base branch, never updated by agents
+  {{ .RepoName }}      Name of the repository directory
+  {{ .Sections.<key> }} Name of a section, e.g. {{ .Sections.solution_proposal }}
+  {{ .Vars.<key> }}    Variable given with --var key=value
+  {{ .DiffRange }}     Commit range to review (reviewer only)
+  {{ section "Solution Proposal" }}  Configured name of a built-in section`,
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the prompts and whether they are overridden",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return handlePromptsList()
+	},
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <role>",
+	Short: "Print the prompt template a role uses",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		builtin, _ := cmd.Flags().GetBool("builtin")
+		return handlePromptsShow(args[0], builtin)
+	},
+}
+
+var promptsEjectCmd = &cobra.Command{
+	Use:   "eject [role...]",
+	Short: "Copy built-in prompts to " + config.PromptsDir + " for editing (all of them by default)",
+	Long: `Copy built-in prompts to ` + config.PromptsDir + ` for editing (all of them by default).
+
+Existing files are kept unless --force is given.
+
+Examples:
+  astropath prompts eject
+  astropath prompts eject developer reviewer
+  astropath prompts eject analyst --force`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		return handlePromptsEject(args, force)
+	},
+}
+
+func init() {
+	promptsShowCmd.Flags().Bool("builtin", false, "Print the built-in prompt even if it is overridden")
+	promptsEjectCmd.Flags().BoolP("force", "f", false, "Overwrite existing prompt files")
+
+	promptsCmd.AddCommand(promptsListCmd)
+	promptsCmd.AddCommand(promptsShowCmd)
+	promptsCmd.AddCommand(promptsEjectCmd)
+}
+
+// promptType returns the overridable prompt of a role
+func promptType(role string) (config.PromptType, error) {
+	var names []string
+	for _, promptType := range config.PromptTypes() {
+		if string(promptType) == role {
+			return promptType, nil
+		}
+		names = append(names, string(promptType))
+	}
+	return "", fmt.Errorf("unknown role '%s' (available: %s)", role, strings.Join(names, ", "))
+}
+
+func handlePromptsList() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, promptType := range config.PromptTypes() {
+		_, source, err := config.LoadPrompt(promptType)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\n", promptType, source)
+	}
+	for _, role := range config.CustomRoles() {
+		fmt.Fprintf(w, "%s\t%s (custom role)\n", role.Name, role.PromptFile)
+	}
+	return w.Flush()
+}
+
+func handlePromptsShow(role string, builtin bool) error {
+	if custom, ok := config.GetRole(role); ok && custom.PromptFile != "" {
+		content, err := os.ReadFile(custom.PromptFile)
+		if err != nil {
+			return fmt.Errorf("reading %s: %v", custom.PromptFile, err)
+		}
+		fmt.Print(string(content))
+		return nil
+	}
+
+	promptType, err := promptType(role)
+	if err != nil {
+		return err
+	}
+
+	text := config.GetPrompt(promptType)
+	if !builtin {
+		text, _, err = config.LoadPrompt(promptType)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Print(text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Println()
+	}
+	return nil
+}
+
+func handlePromptsEject(roles []string, force bool) error {
+	promptTypes := config.PromptTypes()
+	if len(roles) > 0 {
+		promptTypes = nil
+		for _, role := range roles {
+			promptType, err := promptType(role)
+			if err != nil {
+				return err
+			}
+			promptTypes = append(promptTypes, promptType)
+		}
+	}
+
+	if err := os.MkdirAll(config.PromptsDir, 0755); err != nil {
+		return fmt.Errorf("creating %s: %v", config.PromptsDir, err)
+	}
+	for _, promptType := range promptTypes {
+		path := config.PromptPath(promptType)
+		if _, err := os.Stat(path); err == nil && !force {
+			fmt.Printf("%s already exists, skipping (use --force to overwrite).\n", path)
+			continue
+		}
+		if err := os.WriteFile(path, []byte(config.GetPrompt(promptType)), 0644); err != nil {
+			return fmt.Errorf("writing %s: %v", path, err)
+		}
+		fmt.Printf("Wrote the %s prompt to %s.\n", promptType, path)
+	}
+	return nil
+}
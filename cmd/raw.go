@@ -3,9 +3,8 @@ package cmd
 import (
 	"fmt"
 	"strings"
-	"time"
 
-	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/config"
 	"github.com/spf13/cobra"
 )
 
@@ -18,9 +17,13 @@ var rawCmd = &cobra.Command{
 This command allows you to provide a custom prompt directly to Claude.
 The prompt can be provided as multiple arguments or as a quoted string.
 
+Use --resume to continue an agent session with the prompt instead of starting a new one. Session ids
+are printed when agents start, and recorded in the usage ledger and in ` + config.SessionsPath + `.
+
 Examples:
   astropath raw "Help me debug this code"
-  astropath raw Help me debug this code`,
+  astropath raw Help me debug this code
+  astropath raw --resume 3f2a9c1e-... "Now explain the change you made"`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		prompt := strings.Join(args, " ")
@@ -28,38 +31,29 @@ Examples:
 	},
 }
 
+func init() {
+	rawCmd.Flags().StringVar(&resumeSession, "resume", "", "Agent session to continue with the prompt, instead of starting a new one")
+}
+
+// resumeSession holds the --resume flag of the raw command
+var resumeSession string
+
 func claudeRaw(cmd *cobra.Command, prompt string) error {
 	if prompt == "" {
 		return fmt.Errorf("no prompt provided for raw Claude agent")
 	}
 
-	fmt.Println("Launching Claude Raw agent...")
-	
-	// Check if streaming flag is set, default to false for raw
-	useStreaming := streaming || false
-	
-	var done <-chan error
-	if useStreaming {
-		done = claude.RunAgentWithStreaming(prompt)
-	} else {
-		done = claude.RunAgent(prompt)
-	}
-
-	// Give the goroutine a moment to start before returning
-	time.Sleep(100 * time.Millisecond)
-	if useStreaming {
-		fmt.Println("Claude Raw agent launched with streaming. Use Ctrl+C to stop.")
+	if resumeSession != "" {
+		fmt.Printf("Continuing session %s with Claude Raw agent...\n", resumeSession)
 	} else {
-		fmt.Println("Claude Raw agent launched. Use Ctrl+C to stop.")
-	}
-
-	// Wait for the agent to complete
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("Claude Raw agent exited with error: %v", err)
-		}
-		fmt.Println("Claude Raw agent completed successfully.")
-		return nil
+		fmt.Println("Launching Claude Raw agent...")
 	}
+	
+	return launchAgent(cmd, agentLaunch{
+		Label:     "Claude Raw agent",
+		Role:      "raw",
+		Prompt:    prompt,
+		Resume:    resumeSession,
+		Streaming: streaming,
+	})
 }
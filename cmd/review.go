@@ -1,80 +1,339 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
-	"text/template"
-	"time"
+	"os"
+	"regexp"
+	"strings"
 
 	"github.com/fynardo/astropath/internal/claude"
 	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/fynardo/astropath/internal/markdown"
+	"github.com/fynardo/astropath/internal/review"
 	"github.com/spf13/cobra"
 )
 
+// failOn is the severity of the review findings that makes the review fail
+var failOn string
+
+// exportFormat and exportOutput are where the review findings are exported to
+var exportFormat string
+var exportOutput string
+
+// reviewedRange and reviewedBranch are what the last review reviewed, for the exports
+var reviewedRange string
+var reviewedBranch string
+
 // reviewCmd represents the review command
 var reviewCmd = &cobra.Command{
-	Use:   "review [branch]",
+	Use:   "review [branch | range]",
 	Short: "Launch a Claude agent that will review a branch",
 	Long: `Launch a Claude agent that will review a branch.
 
-Defaults to 'main' if no branch is specified.
+The changes of the branch are compared to the base branch, which is detected
+from origin/HEAD (or main/master), use --base to override it.
+If no branch is specified, the current branch is reviewed, unless it is the base branch.
+Astropath checks out the branch before launching the agent.
+
+A commit range (e.g. 'v1.2..HEAD' or 'main...feature') can be given instead of a branch
+to review exactly those changes. No branch is checked out in that case.
+
+Besides the Markdown review, the reviewer lists its findings (severity, file, line, message and
+suggested fix) in a '` + "```json findings" + `' block of its section. Use --fail-on to exit with an error
+when a finding is at or above a severity (major, minor or suggestion), e.g. to gate CI on the review.
+Use --format sarif|json with --output to export the findings, with one rule per category, the file
+locations relative to the repository root and the reviewed commit range. Use '--output -' for stdout.
 
 Examples:
   astropath review
-  astropath review main
-  astropath review feature-branch`,
+  astropath review feature-branch
+  astropath review feature-branch --base develop
+  astropath review HEAD~3..HEAD
+  astropath review --fail-on major
+  astropath review --format sarif --output review.sarif`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		branch := "main" // Default to main branch
+		var branch string
 		if len(args) > 0 {
 			branch = args[0]
 		}
-		return claudeReview(cmd, branch)
+		if err := validateFailOn(failOn); err != nil {
+			return err
+		}
+		if err := validateExport(exportFormat, exportOutput); err != nil {
+			return err
+		}
+		if err := claudeReview(cmd, branch); err != nil {
+			return err
+		}
+		if exportFormat != "" {
+			if err := exportFindings(exportFormat, exportOutput); err != nil {
+				return err
+			}
+		}
+		return checkFindings(failOn)
 	},
 }
 
+func init() {
+	reviewCmd.Flags().StringVar(&baseBranchFlag, "base", "", "Base branch the changes are compared to (detected if not set)")
+	reviewCmd.Flags().StringVar(&exportFormat, "format", "", "Export the review findings in this format: "+strings.Join(review.Formats(), ", "))
+	reviewCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File the findings are exported to, '-' for stdout")
+	reviewCmd.Flags().StringVar(&failOn, "fail-on", "", "Fail when a review finding is at or above this severity: "+strings.Join(review.Severities(), ", "))
+}
+
 func claudeReview(cmd *cobra.Command, branch string) error {
 	fmt.Println("Launching Astropath's Claude reviewer agent...")
 
-	prompt := config.GetPrompt(config.ReviewerPromptType)
-	promptParams := claude.ReviewerParams{BranchName: branch}
+	base := resolveBaseBranch()
+	diffRange := branch
 
-	templ, err := template.New("prompt").Parse(prompt)
+	if git.IsRange(branch) {
+		if !git.ValidRevision(branch) {
+			return fmt.Errorf("invalid commit range '%s'", branch)
+		}
+		fmt.Printf("Reviewing commit range '%s'.\n", branch)
+	} else {
+		resolved, err := resolveBranch(branch, base, "reviewer", false)
+		if err != nil {
+			return err
+		}
+		branch = resolved
+		diffRange = base + "..." + branch
+	}
+
+	// Resolved after checking out the branch, to follow the task bound to it
+	task, err := currentTask()
 	if err != nil {
-		return fmt.Errorf("parsing prompt template: %v", err)
+		return err
+	}
+	if err := checkRequiredSections("reviewer", task); err != nil {
+		return err
 	}
+	reviewedRange, reviewedBranch = diffRange, ""
+	if !git.IsRange(branch) {
+		reviewedBranch = branch
+	}
+
+	promptParams := claude.ReviewerParams{PromptParams: task.promptParams(), DiffRange: diffRange}
+	promptParams.BranchName = branch
+	promptParams.BaseBranch = base
 
-	var buff = bytes.Buffer{}
-	err = templ.Execute(&buff, promptParams)
+	prompt, err := renderPrompt(config.ReviewerPromptType, promptParams)
 	if err != nil {
-		return fmt.Errorf("executing prompt template: %v", err)
+		return err
 	}
 
-	// Check if streaming flag is set, default to true for review
-	useStreaming := streaming || true
-	
-	var done <-chan error
-	if useStreaming {
-		done = claude.RunAgentWithStreaming(buff.String())
-	} else {
-		done = claude.RunAgent(buff.String())
+	return launchAgent(cmd, agentLaunch{
+		Label:     "Astropath's Claude Reviewer agent",
+		Role:      "reviewer",
+		Task:      task.ID,
+		TaskFile:  task.Path,
+		Branch:    promptParams.BranchName,
+		Prompt:    prompt,
+		Streaming: streaming,
+	})
+}
+
+var (
+	reviewGroupRe = regexp.MustCompile(`(?i)^[#*_\s-]*(major issues?|minor issues?|suggestions?)[*_\s]*:?[*_\s]*(.*)$`)
+	bulletRe      = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	noIssueRe     = regexp.MustCompile(`(?i)^[*_\s]*(none|n/?a|nothing( to report)?|no (major )?issues?( found)?)[.!*_\s]*$`)
+)
+
+// validateFailOn checks the value of --fail-on, empty meaning no gate
+func validateFailOn(severity string) error {
+	if severity != "" && !review.ValidSeverity(severity) {
+		return fmt.Errorf("invalid --fail-on value '%s' (available: %s)", severity, strings.Join(review.Severities(), ", "))
 	}
-	
-	// Give the goroutine a moment to start before returning
-	time.Sleep(100 * time.Millisecond)
-	if useStreaming {
-		fmt.Println("Astropath's Claude Reviewer agent launched with streaming. Use Ctrl+C to stop.")
-	} else {
-		fmt.Println("Astropath's Claude Reviewer agent launched. Use Ctrl+C to stop.")
+	return nil
+}
+
+// validateExport checks the --format and --output flags, which go together
+func validateExport(format string, output string) error {
+	if format == "" && output == "" {
+		return nil
 	}
-	
-	// Wait for the agent to complete
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("Claude Reviewer agent exited with error: %v", err)
+	if format == "" || output == "" {
+		return fmt.Errorf("--format and --output must be given together")
+	}
+	for _, known := range review.Formats() {
+		if format == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --format value '%s' (available: %s)", format, strings.Join(review.Formats(), ", "))
+}
+
+// exportFindings writes the findings of the last review to the output file, or to stdout for '-'
+func exportFindings(format string, output string) error {
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+	content, _, err := reviewSection(current.Path)
+	if err != nil {
+		return err
+	}
+	findings, found, err := review.Parse(content)
+	if err != nil {
+		return fmt.Errorf("can't export the review findings: %v", err)
+	}
+	if !found {
+		return fmt.Errorf("can't export the review findings: the '%s' section has no findings block", config.SectionName(config.CodeReviewSection))
+	}
+
+	root, _ := git.RepoRoot()
+	for i := range findings {
+		findings[i].File = review.RelativePath(findings[i].File, root)
+	}
+	report := review.Report{
+		Range:      reviewedRange,
+		Branch:     reviewedBranch,
+		Repository: git.RemoteURL("origin"),
+		Root:       root,
+		Findings:   findings,
+	}
+	from, to := git.RangeEnds(reviewedRange)
+	report.BaseCommit, _ = git.CommitID(from)
+	report.HeadCommit, _ = git.CommitID(to)
+
+	if output == "-" {
+		return review.Write(os.Stdout, format, report)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("creating %s: %v", output, err)
+	}
+	defer file.Close()
+	if err := review.Write(file, format, report); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d review findings to %s.\n", len(findings), output)
+	return nil
+}
+
+// reviewSection returns the content of the Code Review section of the context file, false if it is missing
+func reviewSection(path string) (string, bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("reading %s: %v", path, err)
+	}
+	section := markdown.Parse(string(content)).TopSection(config.SectionName(config.CodeReviewSection))
+	if section == nil {
+		return "", false, nil
+	}
+	return section.Content(), true, nil
+}
+
+// checkFindings reports the findings of the last review, and fails when one is at or above
+// the given severity. Without a severity, missing or invalid findings are only warned about.
+func checkFindings(severity string) error {
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+	content, _, err := reviewSection(current.Path)
+	if err != nil {
+		return err
+	}
+	findings, found, err := review.Parse(content)
+	if err == nil && !found {
+		err = fmt.Errorf("the '%s' section has no findings block", config.SectionName(config.CodeReviewSection))
+	}
+	if err != nil {
+		if severity != "" {
+			return fmt.Errorf("can't check the review findings: %v", err)
 		}
-		fmt.Println("Claude agent completed successfully.")
+		fmt.Fprintf(os.Stderr, "Warning: %v.\n", err)
 		return nil
 	}
+
+	counts := review.Count(findings)
+	fmt.Printf("Review findings: %d major, %d minor, %d suggestions.\n", counts[review.SeverityMajor], counts[review.SeverityMinor], counts[review.SeveritySuggestion])
+	if severity == "" {
+		return nil
+	}
+
+	var failing []review.Finding
+	for _, finding := range findings {
+		if finding.AtLeast(severity) {
+			failing = append(failing, finding)
+		}
+	}
+	if len(failing) == 0 {
+		return nil
+	}
+	for _, finding := range failing {
+		location := ""
+		if finding.Location() != "" {
+			location = finding.Location() + ": "
+		}
+		fmt.Printf("  - [%s] %s%s\n", finding.Severity, location, truncateLine(finding.Message, 120))
+	}
+	return fmt.Errorf("the review has %d findings at or above '%s' severity", len(failing), severity)
+}
+
+// majorIssues returns the major issues of the Code Review section, from its findings block
+// or, when there is none, from the items listed under the Major Issues heading
+func majorIssues(path string) ([]string, error) {
+	content, found, err := reviewSection(path)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	findings, ok, err := review.Parse(content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, reading the major issues from the review text.\n", err)
+	}
+	if ok && err == nil {
+		var issues []string
+		for _, finding := range findings {
+			if finding.Severity != review.SeverityMajor {
+				continue
+			}
+			if finding.Location() != "" {
+				issues = append(issues, finding.Location()+": "+finding.Message)
+			} else {
+				issues = append(issues, finding.Message)
+			}
+		}
+		return issues, nil
+	}
+
+	var issues []string
+	inMajor := false
+	indent := -1
+	for _, line := range strings.Split(content, "\n") {
+		if group := reviewGroupRe.FindStringSubmatch(line); group != nil {
+			// A new group of findings, e.g. '## Major Issues' or '- Major issues: none'
+			inMajor = strings.HasPrefix(strings.ToLower(group[1]), "major")
+			indent = -1
+			if inMajor && group[2] != "" && !noIssueRe.MatchString(group[2]) {
+				issues = append(issues, group[2])
+			}
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			inMajor = false
+			continue
+		}
+		if !inMajor {
+			continue
+		}
+
+		bullet := bulletRe.FindStringSubmatch(line)
+		if bullet == nil {
+			continue
+		}
+		// Only the top level items, nested ones are details
+		if indent < 0 {
+			indent = len(bullet[1])
+		}
+		if len(bullet[1]) <= indent && !noIssueRe.MatchString(bullet[3]) {
+			issues = append(issues, strings.TrimSpace(bullet[3]))
+		}
+	}
+	return issues, nil
 }
@@ -2,15 +2,21 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/markdown"
+	"github.com/fynardo/astropath/internal/proc"
+	"github.com/fynardo/astropath/internal/task"
 	"github.com/spf13/cobra"
 )
 
 var streaming bool
+var backendName string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -20,6 +26,17 @@ var rootCmd = &cobra.Command{
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Load(); err != nil {
+			// The config commands must keep working to fix the files
+			if cmd.Parent() != configCmd {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		applySettings(cmd)
+		return validateFlags()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -28,9 +45,50 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// Exit statuses of Astropath besides 0, see ExitCode
+const (
+	ExitError     = 1   // The command failed
+	ExitLimit     = 124 // An agent reached one of its limits or the task budget is spent, like timeout(1)
+	ExitCancelled = 130 // Stopped with Ctrl+C
+)
+
+// ExitCode returns the exit status for an error returned by Execute
+func ExitCode(err error) int {
+	var limitErr *claude.LimitError
+	var budgetErr *budgetError
+	switch {
+	case proc.Interrupted(err):
+		return ExitCancelled
+	case errors.As(err, &limitErr), errors.As(err, &budgetErr):
+		return ExitLimit
+	default:
+		return ExitError
+	}
+}
+
 func init() {
 	// Add persistent flag for streaming
-	rootCmd.PersistentFlags().BoolVar(&streaming, "streaming", true, "Enable streaming output (overrides command defaults)")
+	rootCmd.PersistentFlags().BoolVar(&streaming, "streaming", true, "Render the agent output as it streams, --streaming=false prints the raw events (overrides the configuration)")
+	// Add persistent flag to choose the task role commands work on
+	rootCmd.PersistentFlags().StringVar(&taskFlag, "task", "", "Task to work on instead of the active one (see 'astropath task')")
+	// Add persistent flag to choose what happens when a role edits sections it doesn't own
+	rootCmd.PersistentFlags().StringVar(&ownershipMode, "ownership", OwnershipWarn, fmt.Sprintf("What to do when a role modifies sections it doesn't own %v", ownershipModes))
+	// Add persistent flag for the number of retries when a role's output fails its checks
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 1, "Times a role is retried when its output fails the checks")
+	// Add persistent flag for the user variables of the prompt templates
+	rootCmd.PersistentFlags().StringArrayVar(&varFlags, "var", nil, "Variable for the prompt templates as key=value, available as {{ .Vars.key }} (repeatable)")
+	// Add persistent flags for the limits of every agent run
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0, "Time limit for each agent run, e.g. 30m (overrides the configuration)")
+	rootCmd.PersistentFlags().IntVar(&maxTurnsFlag, "max-turns", 0, "Turn limit for each agent run (overrides the configuration)")
+	rootCmd.PersistentFlags().Float64Var(&maxCostFlag, "max-cost", 0, "Cost limit in USD for each agent run (overrides the configuration)")
+	rootCmd.PersistentFlags().Int64Var(&maxTokensFlag, "max-tokens", 0, "Token limit for each agent run, cache reads excluded (overrides the configuration)")
+	// Add persistent flags for the budget of the task, shared by all its agent runs
+	rootCmd.PersistentFlags().Float64Var(&taskMaxCostFlag, "task-max-cost", 0, "Cost limit in USD for all the role runs of the task recorded in the usage ledger, raw runs excluded (overrides the configuration)")
+	rootCmd.PersistentFlags().Int64Var(&taskMaxTokensFlag, "task-max-tokens", 0, "Token limit for all the role runs of the task recorded in the usage ledger, raw runs excluded (overrides the configuration)")
+	// Add persistent flag to rewrite the commits that lack the task trailer
+	rootCmd.PersistentFlags().BoolVar(&addTaskTrailer, "add-task-trailer", false, "Rewrite the commits of developer and tester runs that lack the "+config.TaskTrailer+" trailer to add it")
+	// Add persistent flag for the agent backend
+	rootCmd.PersistentFlags().StringVar(&backendName, "backend", "", fmt.Sprintf("Agent backend to use %v (defaults to $ASTROPATH_BACKEND or '%s')", claude.Backends(), claude.DefaultBackend))
 
 	// Add all commands
 	rootCmd.AddCommand(initCmd)
@@ -38,16 +96,85 @@ func init() {
 	rootCmd.AddCommand(developCmd)
 	rootCmd.AddCommand(exploreCmd)
 	rootCmd.AddCommand(reviewCmd)
+	rootCmd.AddCommand(testCmd)
 	rootCmd.AddCommand(rawCmd)
+	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(pipelineCmd)
 	rootCmd.AddCommand(refreshCmd)
+	rootCmd.AddCommand(usageCmd)
+	rootCmd.AddCommand(taskCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(promptsCmd)
+}
+
+// applySettings uses the configuration files as defaults for the persistent flags not given on the command line
+func applySettings(cmd *cobra.Command) {
+	settings := config.Current()
+	flags := cmd.Flags()
+
+	if settings.Backend != "" && !flags.Changed("backend") {
+		backendName = settings.Backend
+	}
+	if settings.Streaming != nil && !flags.Changed("streaming") {
+		streaming = *settings.Streaming
+	}
+	if settings.Ownership != "" && !flags.Changed("ownership") {
+		ownershipMode = settings.Ownership
+	}
+	if settings.Retries != nil && !flags.Changed("retries") {
+		retries = *settings.Retries
+	}
+	if settings.AddTaskTrailer != nil && !flags.Changed("add-task-trailer") {
+		addTaskTrailer = *settings.AddTaskTrailer
+	}
+}
+
+// validateFlags checks the values of the persistent flags
+func validateFlags() error {
+	if err := parsePromptVars(); err != nil {
+		return err
+	}
+	if retries < 0 {
+		return fmt.Errorf("invalid --retries value %d, it can't be negative", retries)
+	}
+	if timeoutFlag < 0 || maxTurnsFlag < 0 || maxCostFlag < 0 || maxTokensFlag < 0 {
+		return fmt.Errorf("--timeout, --max-turns, --max-cost and --max-tokens can't be negative")
+	}
+	if taskMaxCostFlag < 0 || taskMaxTokensFlag < 0 {
+		return fmt.Errorf("--task-max-cost and --task-max-tokens can't be negative")
+	}
+	for _, mode := range ownershipModes {
+		if ownershipMode == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --ownership value '%s' (available: %v)", ownershipMode, ownershipModes)
+}
+
+// agentBackend returns the agent backend selected by the user, set up with the configuration files
+func agentBackend() (claude.Backend, error) {
+	backend, err := claude.NewBackend(backendName)
+	if err != nil {
+		return nil, err
+	}
+
+	if code, ok := backend.(*claude.CodeBackend); ok {
+		settings := config.Current().Claude
+		if settings.Binary != "" {
+			code.Binary = settings.Binary
+		}
+		code.Args = append(code.Args, settings.Args...)
+	}
+	return backend, nil
 }
 
 // initCmd handles the initialization of Astropath
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize Astropath and Claude settings in the current directory",
-	Long:  "Initialize Astropath and Claude settings in the current directory.",
+	Long: `Initialize Astropath and Claude settings in the current directory.
+
+Creates the ASTROPATH.md file, or the context file of a new task when --task is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := handleInit(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -57,12 +184,21 @@ var initCmd = &cobra.Command{
 }
 
 func handleInit() error {
-	// Create ASTROPATH.md file
-	err := os.WriteFile("ASTROPATH.md", []byte(config.AstropathBaseTemplate), 0644)
-	if err != nil {
-		return fmt.Errorf("creating ASTROPATH.md: %v", err)
+	if taskFlag != "" {
+		// Create the task file and make it the active task
+		if _, err := task.Get(taskFlag); err == nil {
+			fmt.Printf("Task '%s' already exists, skipping.\n", taskFlag)
+		} else if err := handleTaskNew(taskFlag, true); err != nil {
+			return err
+		}
+	} else {
+		// Create ASTROPATH.md file
+		err := os.WriteFile(config.AstropathFile, []byte(config.BaseTemplate()), 0644)
+		if err != nil {
+			return fmt.Errorf("creating ASTROPATH.md: %v", err)
+		}
+		fmt.Printf("Created ASTROPATH.md file in the current directory.\n")
 	}
-	fmt.Printf("Created ASTROPATH.md file in the current directory.\n")
 
 	// Create .claude directory if it does not exist
 	if _, err := os.Stat(".claude"); os.IsNotExist(err) {
@@ -98,9 +234,11 @@ var refreshCmd = &cobra.Command{
 	Long: `Refresh clears the ASTROPATH.md file to provide a clean slate for new tasks,
 while preserving the valuable 'Exploration Report' section that contains project context.
 
+When a task is active (or selected with --task), its context file is refreshed instead.
+
 This command will:
 - Preserve the 'Exploration Report' section and its content
-- Clear all other sections (Issue Explanation, Solution Proposal, Implemented Code, Code Review)
+- Clear all other sections (Issue Explanation, Solution Proposal, Implemented Code, Test Report, Code Review)
 - Reset those sections to their empty template state
 
 Use --force to skip the confirmation prompt.`,
@@ -118,14 +256,19 @@ func init() {
 }
 
 func handleRefresh(force bool) error {
-	// Check if ASTROPATH.md exists
-	if _, err := os.Stat("ASTROPATH.md"); os.IsNotExist(err) {
-		return fmt.Errorf("ASTROPATH.md does not exist. Run 'astropath init' first")
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+
+	// Check if the context file exists
+	if _, err := os.Stat(current.Path); os.IsNotExist(err) {
+		return fmt.Errorf("%s does not exist. Run 'astropath init' first", current.Path)
 	}
 
 	// Ask for confirmation unless --force is used
 	if !force {
-		fmt.Print("This will clear all sections except 'Exploration Report'. Are you sure? (y/N): ")
+		fmt.Printf("This will clear all sections of %s except '%s'. Are you sure? (y/N): ", current.Path, config.SectionName(config.ExplorationReportSection))
 		reader := bufio.NewReader(os.Stdin)
 		response, err := reader.ReadString('\n')
 		if err != nil {
@@ -139,57 +282,33 @@ func handleRefresh(force bool) error {
 	}
 
 	// Read current content
-	content, err := os.ReadFile("ASTROPATH.md")
+	content, err := os.ReadFile(current.Path)
 	if err != nil {
-		return fmt.Errorf("reading ASTROPATH.md: %v", err)
+		return fmt.Errorf("reading %s: %v", current.Path, err)
 	}
 
-	// Extract the Exploration Report section
-	explorationReport := extractExplorationReport(string(content))
-
 	// Create new content with preserved Exploration Report and fresh template sections
-	newContent := explorationReport + "\n# Issue Explanation\n\n# Solution Proposal\n\n# Implemented Code\n\n# Code Review\n"
+	newContent := freshContent(string(content))
 
 	// Write the refreshed content
-	err = os.WriteFile("ASTROPATH.md", []byte(newContent), 0644)
+	err = os.WriteFile(current.Path, []byte(newContent), 0644)
 	if err != nil {
-		return fmt.Errorf("writing ASTROPATH.md: %v", err)
+		return fmt.Errorf("writing %s: %v", current.Path, err)
 	}
 
-	fmt.Println("ASTROPATH.md refreshed successfully!")
-	fmt.Println("- Preserved: Exploration Report section")
-	fmt.Println("- Cleared: Issue Explanation, Solution Proposal, Implemented Code, Code Review")
+	fmt.Printf("%s refreshed successfully!\n", current.Path)
+	sections := config.Sections()
+	fmt.Printf("- Preserved: %s section\n", sections[0])
+	fmt.Printf("- Cleared: %s\n", strings.Join(sections[1:], ", "))
 	return nil
 }
 
-func extractExplorationReport(content string) string {
-	lines := strings.Split(content, "\n")
-	var explorationLines []string
-	inExplorationSection := false
-	
-	for _, line := range lines {
-		// Check if we're starting the Exploration Report section
-		if strings.HasPrefix(line, "# Exploration Report") {
-			inExplorationSection = true
-			explorationLines = append(explorationLines, line)
-			continue
-		}
-		
-		// Check if we've hit another section (any line starting with #)
-		if inExplorationSection && strings.HasPrefix(line, "# ") && !strings.HasPrefix(line, "# Exploration Report") {
-			break
-		}
-		
-		// If we're in the exploration section, add the line
-		if inExplorationSection {
-			explorationLines = append(explorationLines, line)
-		}
-	}
-	
-	// If no exploration report was found, create a default one
-	if len(explorationLines) == 0 {
-		return "# Exploration Report\n"
+// freshContent returns the context file template, keeping the Exploration Report of the given content
+func freshContent(content string) string {
+	fresh := markdown.Parse(config.BaseTemplate())
+	explorationReport := config.SectionName(config.ExplorationReportSection)
+	if report, ok := markdown.Parse(content).Get(explorationReport); ok {
+		fresh.Set(explorationReport, report)
 	}
-	
-	return strings.Join(explorationLines, "\n")
+	return fresh.String()
 }
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/proc"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"error", errors.New("branch 'feature' does not exist"), ExitError},
+		{"interrupted", fmt.Errorf("step 'develop': %w", &proc.SignalError{Signal: os.Interrupt}), ExitCancelled},
+		{"limit", fmt.Errorf("claude agent error: %w", &claude.LimitError{Timeout: time.Minute}), ExitLimit},
+		{"budget", &budgetError{budget: &taskBudget{}}, ExitLimit},
+	}
+	for _, test := range tests {
+		if got := ExitCode(test.err); got != test.want {
+			t.Errorf("ExitCode(%s) = %d, want %d", test.name, got, test.want)
+		}
+	}
+	if ExitCancelled != 130 {
+		t.Errorf("ExitCancelled = %d, want 130 like shells for SIGINT", ExitCancelled)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [role] [branch]",
+	Short: "Launch an agent for any role, including the custom roles of the configuration",
+	Long: `Launch an agent for any role, including the custom roles of the configuration.
+
+Without arguments, the available roles are listed. Custom roles are declared under 'roles'
+in ` + config.ProjectConfigPath + `, for example:
+
+  roles:
+    security-auditor:
+      description: Audits the changes for security issues
+      prompt: .astropath/roles/security-auditor.md
+      section: Security Audit
+      requires: [Solution Proposal, Implemented Code]
+      allowed_tools: ["Bash(git diff:*)", Read, Grep]
+      model: opus
+
+The prompt file is a template with the same parameters as the built-in prompts (see 'astropath prompts'),
+and is appended to the instructions shared by all the roles about the context file.
+Roles with 'branch: true' commit changes, so they work on a feature branch like the developer.
+Other roles stay on the current branch unless a branch is given.
+
+Examples:
+  astropath run
+  astropath run security-auditor
+  astropath run docs-writer my-feature-branch`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return handleRoleList()
+		}
+		var branch string
+		if len(args) > 1 {
+			branch = args[1]
+		}
+		return runRole(cmd, args[0], branch)
+	},
+}
+
+func init() {
+	runCmd.Flags().StringVar(&baseBranchFlag, "base", "", "Base branch new branches are created from and compared to (detected if not set)")
+}
+
+func handleRoleList() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, role := range config.Roles() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", role.Name, role.Section, role.Description)
+	}
+	return w.Flush()
+}
+
+// runRole launches the agent of a built-in or custom role on the given branch
+func runRole(cmd *cobra.Command, name string, branch string) error {
+	switch name {
+	case "explorer":
+		return claudeExplore(cmd)
+	case "analyst":
+		return claudeAnalyze(cmd)
+	case "developer":
+		return claudeDevelop(cmd, branch)
+	case "tester":
+		return claudeTest(cmd, branch)
+	case "reviewer":
+		return claudeReview(cmd, branch)
+	}
+
+	role, ok := config.GetRole(name)
+	if !ok {
+		var names []string
+		for _, role := range config.Roles() {
+			names = append(names, role.Name)
+		}
+		return fmt.Errorf("unknown role '%s' (available: %s)", name, strings.Join(names, ", "))
+	}
+	return claudeRun(cmd, role, branch)
+}
+
+// claudeRun launches the agent of a custom role
+func claudeRun(cmd *cobra.Command, role config.Role, branch string) error {
+	label := fmt.Sprintf("Astropath's %s agent", role.Name)
+	fmt.Printf("Launching %s...\n", label)
+
+	base := resolveBaseBranch()
+	if role.Branch {
+		// Check the role can run before creating its branch, not to leave the user on a new empty one
+		current, err := currentTask()
+		if err != nil {
+			return err
+		}
+		if err := checkRequiredSections(role.Name, current); err != nil {
+			return err
+		}
+	}
+	if role.Branch || branch != "" {
+		resolved, err := resolveBranch(branch, base, role.Name, role.Branch)
+		if err != nil {
+			return err
+		}
+		branch = resolved
+	}
+
+	// Resolved after checking out the branch, to follow the task bound to it
+	task, err := currentTask()
+	if err != nil {
+		return err
+	}
+	if err := checkRequiredSections(role.Name, task); err != nil {
+		return err
+	}
+
+	promptParams := task.promptParams()
+	if branch != "" {
+		promptParams.BranchName = branch
+	}
+	promptParams.BaseBranch = base
+	prompt, err := renderRolePrompt(role, promptParams)
+	if err != nil {
+		return err
+	}
+
+	return launchAgent(cmd, agentLaunch{
+		Label:     label,
+		Role:      role.Name,
+		Task:      task.ID,
+		TaskFile:  task.Path,
+		Branch:    promptParams.BranchName,
+		Prompt:    prompt,
+		Streaming: streaming,
+	})
+}
@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/claude"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/fynardo/astropath/internal/task"
+	"github.com/spf13/cobra"
+)
+
+// taskFlag holds the persistent --task flag
+var taskFlag string
+
+// taskContext is the context file role commands read and write
+type taskContext struct {
+	ID   string // Empty when no task is selected and the default ASTROPATH.md is used
+	Path string
+}
+
+// promptParams returns the template parameters of the prompts for this task.
+// The branch is the checked out one, commands working on another branch override it
+func (t taskContext) promptParams() claude.PromptParams {
+	branch, _ := git.CurrentBranch()
+	return claude.PromptParams{
+		TaskID:     t.ID,
+		TaskFile:   "./" + t.Path,
+		BranchName: branch,
+		BaseBranch: resolveBaseBranch(),
+		RepoName:   repoName(),
+		Sections:   config.SectionsByKey(),
+		Vars:       promptVars,
+	}
+}
+
+// currentTask returns the task selected with --task, the task bound to the current branch,
+// the active task, or the default ASTROPATH.md, in that order
+func currentTask() (taskContext, error) {
+	id := taskFlag
+	if id == "" {
+		bound, err := branchTask()
+		if err != nil {
+			return taskContext{}, err
+		}
+		id = bound
+	}
+	if id == "" {
+		active, err := task.Active()
+		if err != nil {
+			return taskContext{}, err
+		}
+		id = active
+	}
+
+	if id == "" {
+		return taskContext{Path: config.AstropathFile}, nil
+	}
+
+	t, err := task.Get(id)
+	if err != nil {
+		return taskContext{}, err
+	}
+	return taskContext{ID: t.ID, Path: t.Path}, nil
+}
+
+// branchTask returns the task bound to the checked out branch, if any
+func branchTask() (string, error) {
+	branch, err := git.CurrentBranch()
+	if err != nil || branch == "" {
+		// Not in a git repository or detached HEAD: no branch to follow
+		return "", nil
+	}
+	return task.ForBranch(branch)
+}
+
+// bindTask attaches the task to the branch unless the branch already has one
+func bindTask(current taskContext, branch string) error {
+	if current.ID == "" || branch == "" {
+		return nil
+	}
+	bound, err := task.ForBranch(branch)
+	if err != nil || bound != "" {
+		return err
+	}
+	if err := task.Bind(current.ID, branch); err != nil {
+		return err
+	}
+	fmt.Printf("Task '%s' is now bound to branch '%s'.\n", current.ID, branch)
+	return nil
+}
+
+// addTaskTrailer holds the persistent --add-task-trailer flag
+var addTaskTrailer bool
+
+// tagTaskCommits checks that the commits made on the branch since start carry the task trailer. The agent adds it
+// when it commits; the commits it forgot are only rewritten to add it with --add-task-trailer, otherwise they are
+// reported. Commits brought in from base or by merges are left alone. Failing to do so only prints a warning
+func tagTaskCommits(current taskContext, start string, base string) {
+	if current.ID == "" || start == "" {
+		return
+	}
+	trailer := fmt.Sprintf("%s: %s", config.TaskTrailer, current.ID)
+
+	if !addTaskTrailer {
+		missing, err := git.MissingTrailer(start, base, config.TaskTrailer, current.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not check the %s trailer of the new commits: %v\n", config.TaskTrailer, err)
+		} else if len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: commits %s lack the '%s' trailer, use --add-task-trailer to rewrite them with it.\n", strings.Join(missing, ", "), trailer)
+		}
+		return
+	}
+
+	tagged, err := git.AddTrailer(start, base, config.TaskTrailer, current.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not add the %s trailer to the new commits: %v\n", config.TaskTrailer, err)
+		return
+	}
+	if tagged > 0 {
+		fmt.Printf("Added the '%s' trailer to %d commits.\n", trailer, tagged)
+	}
+}
+
+// taskCmd represents the task command
+var taskCmd = &cobra.Command{
+	Use:   "task",
+	Short: "Manage the tasks tracked in this working copy",
+	Long: `Manage the tasks tracked in this working copy.
+
+Each task has its own context file under ` + config.TasksDir + `/<task-id>.md, with the same
+sections as ASTROPATH.md. Role commands work on the task chosen with --task, otherwise on the
+task bound to the checked out branch, otherwise on the active task. When none applies, the
+default ASTROPATH.md file is used.
+
+The developer role binds the task to the branch it works on, so checking out that branch
+selects the task again. The developer and tester roles add an '` + config.TaskTrailer + `: <task-id>' trailer
+to their commits, find them with: git log --grep '` + config.TaskTrailer + `: <task-id>'
+Astropath reports the commits of a run that lack it. With --add-task-trailer (or add_task_trailer: true
+in the configuration) it rewrites them to add it instead. Only the commits the run made on the branch
+are tagged, not merge commits nor the commits they bring in, and signed commits are never rewritten.`,
+}
+
+var taskNewCmd = &cobra.Command{
+	Use:   "new [task-id]",
+	Short: "Create a new task and make it the active one",
+	Long: `Create a new task and make it the active one.
+
+The Exploration Report of the current task (or ASTROPATH.md) is copied to the new task,
+all other sections start empty. If no id is provided, one is generated from the current time.
+
+Examples:
+  astropath task new
+  astropath task new fix-login-timeout`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := "task-" + time.Now().Format("20060102-150405")
+		if len(args) > 0 {
+			id = args[0]
+		}
+		noSwitch, _ := cmd.Flags().GetBool("no-switch")
+		return handleTaskNew(id, !noSwitch)
+	},
+}
+
+var taskListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tasks and their branches, marking the current one",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return handleTaskList()
+	},
+}
+
+var taskSwitchCmd = &cobra.Command{
+	Use:   "switch [task-id]",
+	Short: "Make a task the active one",
+	Long: `Make a task the active one.
+
+Use --default to go back to the default ASTROPATH.md file.
+
+Examples:
+  astropath task switch fix-login-timeout
+  astropath task switch --default`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		useDefault, _ := cmd.Flags().GetBool("default")
+		if useDefault == (len(args) > 0) {
+			return fmt.Errorf("provide either a task id or --default")
+		}
+		var id string
+		if len(args) > 0 {
+			id = args[0]
+		}
+		return handleTaskSwitch(id)
+	},
+}
+
+var taskBindCmd = &cobra.Command{
+	Use:   "bind [branch]",
+	Short: "Bind the current task to a branch (the checked out one by default)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var branch string
+		if len(args) > 0 {
+			branch = args[0]
+		}
+		return handleTaskBind(branch)
+	},
+}
+
+var taskShowCmd = &cobra.Command{
+	Use:   "show [task-id]",
+	Short: "Print the context file of a task (the current one by default)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			taskFlag = args[0]
+		}
+		return handleTaskShow()
+	},
+}
+
+func init() {
+	taskNewCmd.Flags().Bool("no-switch", false, "Don't make the new task the active one")
+	taskSwitchCmd.Flags().Bool("default", false, "Clear the active task and use the default ASTROPATH.md")
+
+	taskCmd.AddCommand(taskNewCmd)
+	taskCmd.AddCommand(taskListCmd)
+	taskCmd.AddCommand(taskSwitchCmd)
+	taskCmd.AddCommand(taskShowCmd)
+	taskCmd.AddCommand(taskBindCmd)
+}
+
+func handleTaskNew(id string, activate bool) error {
+	// Carry the project context over from the current task
+	var content []byte
+	if current, err := currentTask(); err == nil {
+		content, _ = os.ReadFile(current.Path)
+	}
+
+	t, err := task.Create(id, freshContent(string(content)))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created task '%s' in %s.\n", t.ID, t.Path)
+
+	if activate {
+		if err := task.SetActive(t.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Task '%s' is now the active task.\n", t.ID)
+	}
+	return nil
+}
+
+func handleTaskList() error {
+	tasks, err := task.List()
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		fmt.Println("No tasks yet. Create one with 'astropath task new <task-id>'.")
+		return nil
+	}
+
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range tasks {
+		marker := " "
+		if t.ID == current.ID {
+			marker = "*"
+		}
+		branches, err := task.Branches(t.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%s\n", marker, t.ID, t.ModTime.Format("2006-01-02 15:04"), t.Path, strings.Join(branches, ", "))
+	}
+	if current.ID == "" {
+		fmt.Fprintf(w, "* (default)\t\t%s\t\n", config.AstropathFile)
+	}
+	return w.Flush()
+}
+
+func handleTaskSwitch(id string) error {
+	if err := task.SetActive(id); err != nil {
+		return err
+	}
+	if id == "" {
+		fmt.Printf("No active task, role commands will use %s.\n", config.AstropathFile)
+	} else {
+		fmt.Printf("Task '%s' is now the active task.\n", id)
+	}
+
+	// The task bound to the checked out branch takes precedence over the active one
+	if bound, err := branchTask(); err == nil && bound != "" && bound != id {
+		fmt.Printf("Note: the checked out branch is bound to task '%s', which is used while it is checked out.\n", bound)
+	}
+	return nil
+}
+
+func handleTaskBind(branch string) error {
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+	if current.ID == "" {
+		return fmt.Errorf("no task selected, create one with 'astropath task new' or use --task")
+	}
+
+	if branch == "" {
+		branch, err = git.CurrentBranch()
+		if err != nil {
+			return err
+		}
+		if branch == "" {
+			return fmt.Errorf("HEAD is detached, specify the branch to bind")
+		}
+	}
+	if !git.BranchExists(branch) {
+		return fmt.Errorf("branch '%s' does not exist", branch)
+	}
+
+	if err := task.Bind(current.ID, branch); err != nil {
+		return err
+	}
+	fmt.Printf("Task '%s' is now bound to branch '%s'.\n", current.ID, branch)
+	return nil
+}
+
+func handleTaskShow() error {
+	current, err := currentTask()
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(current.Path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", current.Path, err)
+	}
+	fmt.Print(string(content))
+	return nil
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git/gittest"
+	"github.com/fynardo/astropath/internal/task"
+)
+
+func TestTagTaskCommits(t *testing.T) {
+	tests := []struct {
+		name    string
+		add     bool
+		task    string
+		wantTag bool
+	}{
+		{"reported only", false, "parser", false},
+		{"added", true, "parser", true},
+		{"default context file", true, "", false},
+	}
+
+	defer func(add bool) { addTaskTrailer = add }(addTaskTrailer)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gittest.NewRepo(t)
+			gittest.Git(t, "checkout", "-q", "-b", "feature")
+			start := gittest.Git(t, "rev-parse", "HEAD")
+			gittest.Commit(t, "Change")
+
+			addTaskTrailer = test.add
+			tagTaskCommits(taskContext{ID: test.task}, start, "main")
+			message := gittest.Git(t, "log", "-1", "--format=%B")
+			if tagged := strings.Contains(message, "Astropath-Task: parser"); tagged != test.wantTag {
+				t.Errorf("commit message = %q, want the trailer %v", message, test.wantTag)
+			}
+		})
+	}
+}
+
+func TestCurrentTask(t *testing.T) {
+	gittest.NewRepo(t)
+	for _, id := range []string{"active", "bound", "flag"} {
+		if _, err := task.Create(id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := task.Bind("bound", "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		active string
+		branch string
+		flag   string
+		want   string
+	}{
+		{name: "default context file", branch: "main", want: ""},
+		{name: "active task", active: "active", branch: "main", want: "active"},
+		{name: "branch binding over the active task", active: "active", branch: "feature", want: "bound"},
+		{name: "flag over everything", active: "active", branch: "feature", flag: "flag", want: "flag"},
+	}
+
+	defer func(flag string) { taskFlag = flag }(taskFlag)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := task.SetActive(test.active); err != nil {
+				t.Fatal(err)
+			}
+			gittest.Git(t, "checkout", "-q", "-B", test.branch)
+			taskFlag = test.flag
+
+			current, err := currentTask()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantPath := config.AstropathFile
+			if test.want != "" {
+				wantPath = task.Path(test.want)
+			}
+			if current.ID != test.want || current.Path != wantPath {
+				t.Errorf("currentTask() = %+v, want %q in %s", current, test.want, wantPath)
+			}
+		})
+	}
+
+	taskFlag = "missing"
+	if _, err := currentTask(); err == nil {
+		t.Error("currentTask() accepted a missing task")
+	}
+}
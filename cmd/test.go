@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test [branch]",
+	Short: "Launch a Claude agent that will write and run tests for a branch",
+	Long: `Launch a Claude agent that will write and run tests for a branch.
+
+The agent writes or extends tests for the changes on the branch, runs them and
+records the results and coverage delta in the 'Test Report' section of ASTROPATH.md.
+
+If no branch name is provided, the current branch is tested, unless it is the base branch.
+Astropath checks out the branch before launching the agent.
+
+The base branch is detected from origin/HEAD (or main/master), use --base to override it.
+
+Examples:
+  astropath test
+  astropath test my-feature-branch`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var branch string
+		if len(args) > 0 {
+			branch = args[0]
+		}
+		return claudeTest(cmd, branch)
+	},
+}
+
+func init() {
+	testCmd.Flags().StringVar(&baseBranchFlag, "base", "", "Base branch the changes are compared to (detected if not set)")
+}
+
+func claudeTest(cmd *cobra.Command, branch string) error {
+	fmt.Println("Launching Astropath's Claude Tester agent...")
+
+	base := resolveBaseBranch()
+	branch, err := resolveBranch(branch, base, "tester", false)
+	if err != nil {
+		return err
+	}
+
+	// Resolved after checking out the branch, to follow the task bound to it
+	task, err := currentTask()
+	if err != nil {
+		return err
+	}
+	if err := checkRequiredSections("tester", task); err != nil {
+		return err
+	}
+
+	promptParams := task.promptParams()
+	promptParams.BranchName = branch
+	promptParams.BaseBranch = base
+	prompt, err := renderPrompt(config.TesterPromptType, promptParams)
+	if err != nil {
+		return err
+	}
+
+	start, _ := git.CommitID("HEAD")
+	err = launchAgent(cmd, agentLaunch{
+		Label:     "Astropath's Claude Tester agent",
+		Role:      "tester",
+		Task:      task.ID,
+		TaskFile:  task.Path,
+		Branch:    branch,
+		Prompt:    prompt,
+		Streaming: streaming,
+	})
+	tagTaskCommits(task, start, base)
+	return err
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var usageBy []string
+var usageSince string
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show the tokens, cost and time spent by agent runs",
+	Long: `Show the tokens, cost and time spent by agent runs.
+
+Every agent run appends a record to ` + config.UsageLedgerPath + `.
+This command aggregates those records by day, role, task, branch, command or model.
+
+Examples:
+  astropath usage
+  astropath usage --by role
+  astropath usage --by day,role
+  astropath usage --by task --since 2024-06-01`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showUsage(usageBy, usageSince)
+	},
+}
+
+func init() {
+	usageCmd.Flags().StringSliceVar(&usageBy, "by", []string{"day"}, fmt.Sprintf("Fields to aggregate by %v", usage.GroupFields()))
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "Only include runs since this date (YYYY-MM-DD)")
+}
+
+func showUsage(by []string, since string) error {
+	records, err := usage.Load(config.UsageLedgerPath)
+	if err != nil {
+		return err
+	}
+
+	if since != "" {
+		sinceTime, err := time.ParseInLocation("2006-01-02", since, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --since date %q: %v", since, err)
+		}
+		var filtered []usage.Record
+		for _, record := range records {
+			if !record.Time.Before(sinceTime) {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No agent runs recorded yet.")
+		return nil
+	}
+
+	summaries, err := usage.Aggregate(records, by)
+	if err != nil {
+		return err
+	}
+
+	var total usage.Summary
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tRUNS\tFAILED\tIN\tOUT\tCACHE WRITE\tCACHE READ\tCOST\tTIME\t\n", strings.ToUpper(strings.Join(by, " / ")))
+	for _, s := range summaries {
+		printSummary(w, s)
+		total.Runs += s.Runs
+		total.Failed += s.Failed
+		total.InputTokens += s.InputTokens
+		total.OutputTokens += s.OutputTokens
+		total.CacheCreationTokens += s.CacheCreationTokens
+		total.CacheReadTokens += s.CacheReadTokens
+		total.CostUSD += s.CostUSD
+		total.Duration += s.Duration
+	}
+	total.Key = "TOTAL"
+	printSummary(w, total)
+	return w.Flush()
+}
+
+func printSummary(w *tabwriter.Writer, s usage.Summary) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t$%.4f\t%s\t\n",
+		s.Key, s.Runs, s.Failed, s.InputTokens, s.OutputTokens,
+		s.CacheCreationTokens, s.CacheReadTokens, s.CostUSD, s.Duration.Round(time.Second))
+}
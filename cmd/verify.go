@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/markdown"
+)
+
+// retries holds the persistent --retries flag
+var retries int
+
+type CorrectionParams struct {
+	Section  string
+	TaskFile string
+	Problems []string
+}
+
+// verifyOutput checks the section a role owns after it runs, and returns the problems found
+func verifyOutput(role config.Role, path string) ([]string, error) {
+	if role.Section == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+
+	section := markdown.Parse(string(content)).TopSection(role.Section)
+	if section == nil {
+		return []string{fmt.Sprintf("the section '%s' is missing", role.Section)}, nil
+	}
+	if isPlaceholder(section, markdown.Parse(config.BaseTemplate()).TopSection(role.Section)) {
+		return []string{fmt.Sprintf("the section '%s' is empty", role.Section)}, nil
+	}
+
+	var problems []string
+	for _, check := range role.Checks {
+		re, err := regexp.Compile(check.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output check pattern for %s: %v", check.Description, err)
+		}
+		if !re.MatchString(section.Content()) {
+			problems = append(problems, fmt.Sprintf("the section '%s' doesn't contain %s", role.Section, check.Description))
+		}
+	}
+	return problems, nil
+}
+
+// correctionPrompt builds the prompt used to retry a role whose output failed the checks: the correction
+// appended to the original prompt, or the correction alone when the failed session is continued (empty prompt)
+func correctionPrompt(prompt string, role config.Role, current taskContext, problems []string) (string, error) {
+	params := CorrectionParams{Section: role.Section, TaskFile: current.promptParams().TaskFile, Problems: problems}
+	correction, err := renderTemplate(config.CorrectionPrompt, params)
+	if err != nil {
+		return "", err
+	}
+	if prompt == "" {
+		return correction, nil
+	}
+	return prompt + "\n" + correction, nil
+}
@@ -1,14 +1,43 @@
 package config
 
+// AstropathDir is the directory where Astropath keeps its local state
+const AstropathDir = ".astropath"
 
-const AstropathBaseTemplate =
-`# Exploration Report
+// AstropathFile is the shared context file used when no task is selected
+const AstropathFile = "ASTROPATH.md"
 
-# Issue Explanation
+// TasksDir holds one context file per task, named <task-id>.md
+const TasksDir = AstropathDir + "/tasks"
 
-# Solution Proposal
+// ActiveTaskPath stores the id of the task role commands work on
+const ActiveTaskPath = AstropathDir + "/active-task"
 
-# Implemented Code
+// BranchTasksPath maps git branches to the task they were created for
+const BranchTasksPath = AstropathDir + "/branches.json"
 
-# Code Review
-`
+// SessionsPath stores the last agent session of each role per task, so it can be continued
+const SessionsPath = AstropathDir + "/sessions.json"
+
+// TaskTrailer is the git trailer that links commits to their task
+const TaskTrailer = "Astropath-Task"
+
+// UsageLedgerPath is the file where every agent run appends its usage record
+const UsageLedgerPath = AstropathDir + "/usage.jsonl"
+
+// BranchNameTemplate names the branches Astropath creates when none is given.
+// Available fields: .Role, .Date (YYYYMMDD) and .Time (HHMMSS)
+const BranchNameTemplate = "astropath/{{ .Date }}-{{ .Time }}"
+
+// Sections of the context file
+const (
+	ExplorationReportSection = "Exploration Report"
+	IssueExplanationSection  = "Issue Explanation"
+	SolutionProposalSection  = "Solution Proposal"
+	ImplementedCodeSection   = "Implemented Code"
+	TestReportSection        = "Test Report"
+	CodeReviewSection        = "Code Review"
+	VerificationSection      = "Verification Report" // Written by Astropath with the output of the verification commands
+)
+
+// PipelineStateDir keeps the state of the last pipeline run of each task, to resume it
+const PipelineStateDir = AstropathDir + "/pipelines"
@@ -0,0 +1,399 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigPath is the project configuration file, at the root of the working copy
+const ProjectConfigPath = ".astropath.yaml"
+
+// tomlExt is the extension of configuration files written in TOML instead of YAML
+const tomlExt = ".toml"
+
+// Settings is the content of an Astropath configuration file.
+// The user configuration is loaded first, the project configuration overrides it.
+type Settings struct {
+	Backend        string                  `yaml:"backend,omitempty"`          // Agent backend, see --backend
+	BaseBranch     string                  `yaml:"base_branch,omitempty"`      // Base branch, detected when empty
+	BranchTemplate string                  `yaml:"branch_template,omitempty"`  // Template for the branches Astropath creates
+	Streaming      *bool                   `yaml:"streaming,omitempty"`        // Default of --streaming
+	Ownership      string                  `yaml:"ownership,omitempty"`        // Default of --ownership
+	Retries        *int                    `yaml:"retries,omitempty"`          // Default of --retries
+	AddTaskTrailer *bool                   `yaml:"add_task_trailer,omitempty"` // Default of --add-task-trailer
+	Timeout        Duration                `yaml:"timeout,omitempty"`          // Time limit for every agent run
+	MaxTurns       int                     `yaml:"max_turns,omitempty"`        // Turn limit for every agent run
+	MaxCost        float64                 `yaml:"max_cost,omitempty"`         // Cost limit in USD for every agent run
+	MaxTokens      int64                   `yaml:"max_tokens,omitempty"`       // Token limit for every agent run
+	TaskBudget     BudgetSettings          `yaml:"task_budget,omitempty"`      // Limits of the total spend of a task
+	Claude         ClaudeSettings          `yaml:"claude,omitempty"`
+	Roles          map[string]RoleSettings `yaml:"roles,omitempty"`
+	Sections       SectionNames            `yaml:"sections,omitempty"`
+	Pipelines      map[string]Pipeline     `yaml:"pipelines,omitempty"`
+	Verify         VerifySettings          `yaml:"verify,omitempty"`
+}
+
+// ClaudeSettings configures the Claude Code backend
+type ClaudeSettings struct {
+	Binary string   `yaml:"binary,omitempty"` // Path or name of the claude executable
+	Args   []string `yaml:"args,omitempty"`   // Extra arguments for every invocation
+}
+
+// RoleSettings configures a built-in role, or declares a custom one
+type RoleSettings struct {
+	Model        string   `yaml:"model,omitempty"`         // Model the role runs with
+	Timeout      Duration `yaml:"timeout,omitempty"`       // Time limit for the role, overrides the global one
+	MaxTurns     int      `yaml:"max_turns,omitempty"`     // Turn limit for the role, overrides the global one
+	MaxCost      float64  `yaml:"max_cost,omitempty"`      // Cost limit in USD for the role, overrides the global one
+	MaxTokens    int64    `yaml:"max_tokens,omitempty"`    // Token limit for the role, overrides the global one
+	AllowedTools []string `yaml:"allowed_tools,omitempty"` // Tools the agent may use without asking, e.g. "Bash(go test:*)"
+
+	// Custom roles only
+	Description string   `yaml:"description,omitempty"`
+	Prompt      string   `yaml:"prompt,omitempty"`   // Prompt template file, relative to the project root
+	Section     string   `yaml:"section,omitempty"`  // Section of the context file the role owns
+	Requires    []string `yaml:"requires,omitempty"` // Sections that must be filled in before the role runs
+	Branch      bool     `yaml:"branch,omitempty"`   // The role commits changes, so it works on a feature branch like the developer
+}
+
+// BudgetSettings limits what all the agent runs of a task spend together, as recorded in the usage ledger
+type BudgetSettings struct {
+	MaxCost   float64 `yaml:"max_cost,omitempty"`   // USD
+	MaxTokens int64   `yaml:"max_tokens,omitempty"` // Input, output and cache write tokens
+}
+
+// customRoleNameRe matches the names allowed for custom roles
+var customRoleNameRe = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// SectionNames renames the sections of the context file
+type SectionNames struct {
+	ExplorationReport  string `yaml:"exploration_report,omitempty"`
+	IssueExplanation   string `yaml:"issue_explanation,omitempty"`
+	SolutionProposal   string `yaml:"solution_proposal,omitempty"`
+	ImplementedCode    string `yaml:"implemented_code,omitempty"`
+	TestReport         string `yaml:"test_report,omitempty"`
+	CodeReview         string `yaml:"code_review,omitempty"`
+	VerificationReport string `yaml:"verification_report,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "30m" in configuration files
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration '%s'", value.Line, value.Value)
+	}
+	if parsed <= 0 {
+		return fmt.Errorf("line %d: the duration '%s' must be positive", value.Line, value.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// current holds the settings loaded by Load
+var current = &Settings{}
+
+// Current returns the loaded settings.
+func Current() *Settings {
+	return current
+}
+
+// UserConfigPath returns the user configuration file, e.g. ~/.config/astropath/config.yaml
+func UserConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "astropath", "config.yaml")
+}
+
+// ProjectConfigFile returns the project configuration file: .astropath.toml when it exists, else ProjectConfigPath
+func ProjectConfigFile() (string, error) {
+	return configFile(ProjectConfigPath)
+}
+
+// UserConfigFile returns the user configuration file: config.toml when it exists, else UserConfigPath.
+// It is empty when there is no user configuration directory.
+func UserConfigFile() (string, error) {
+	path := UserConfigPath()
+	if path == "" {
+		return "", nil
+	}
+	return configFile(path)
+}
+
+// configFile returns the TOML variant of a YAML configuration file when it exists, failing when both do
+func configFile(path string) (string, error) {
+	tomlPath := strings.TrimSuffix(path, filepath.Ext(path)) + tomlExt
+	if _, err := os.Stat(tomlPath); err != nil {
+		return path, nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("found both %s and %s, keep only one of them", path, tomlPath)
+	}
+	return tomlPath, nil
+}
+
+// IsTOML reports whether a configuration file is written in TOML
+func IsTOML(path string) bool {
+	return filepath.Ext(path) == tomlExt
+}
+
+// Load reads the user and project configuration files and merges them, see Merge.
+// Missing files are ignored.
+func Load() error {
+	settings, err := MergeWith("", nil)
+	if err != nil {
+		return err
+	}
+	current = settings
+	return nil
+}
+
+// MergeWith reads the user and project configuration files, merges them and validates the result.
+// The settings given for path are used instead of the content of that file, to check an edit before writing it.
+func MergeWith(path string, edited *Settings) (*Settings, error) {
+	userFile, err := UserConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	projectFile, err := ProjectConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &Settings{}
+	for _, file := range []string{userFile, projectFile} {
+		if file == "" {
+			continue
+		}
+		fileSettings := edited
+		if file != path {
+			var err error
+			if fileSettings, err = LoadFile(file); err != nil {
+				return nil, err
+			}
+		}
+		settings = Merge(settings, fileSettings)
+	}
+	if err := Validate(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Validate checks the settings that can't be checked while parsing
+func Validate(settings *Settings) error {
+	if err := validateRoles(settings); err != nil {
+		return err
+	}
+	if err := validateVerify(settings); err != nil {
+		return err
+	}
+	return validatePipelines(settings)
+}
+
+// validateRoles checks the role settings: built-in roles can only be tuned, custom roles need a prompt
+func validateRoles(settings *Settings) error {
+	if settings.MaxTurns < 0 || settings.MaxCost < 0 || settings.MaxTokens < 0 || settings.Timeout < 0 {
+		return fmt.Errorf("timeout, max_turns, max_cost and max_tokens can't be negative")
+	}
+	if settings.TaskBudget.MaxCost < 0 || settings.TaskBudget.MaxTokens < 0 {
+		return fmt.Errorf("task_budget: max_cost and max_tokens can't be negative")
+	}
+	for name, role := range settings.Roles {
+		if role.MaxTurns < 0 || role.MaxCost < 0 || role.MaxTokens < 0 || role.Timeout < 0 {
+			return fmt.Errorf("roles.%s: timeout, max_turns, max_cost and max_tokens can't be negative", name)
+		}
+		if isBuiltinRole(name) {
+			if role.Prompt != "" || role.Section != "" || len(role.Requires) > 0 || role.Branch || role.Description != "" {
+				return fmt.Errorf("roles.%s: only model, timeout, max_turns, max_cost, max_tokens and allowed_tools can be set for a built-in role, override its prompt in %s", name, PromptsDir)
+			}
+			continue
+		}
+		if !customRoleNameRe.MatchString(name) || name == "raw" || name == "run" {
+			return fmt.Errorf("roles.%s: invalid role name, use lowercase letters, digits and dashes", name)
+		}
+		if role.Prompt == "" {
+			return fmt.Errorf("roles.%s: a custom role needs a prompt file", name)
+		}
+	}
+	return nil
+}
+
+// LoadFile reads a single configuration file. A missing file has empty settings.
+func LoadFile(path string) (*Settings, error) {
+	settings := &Settings{}
+	if err := decodeFile(path, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// decodeFile reads a configuration file on top of the given settings
+func decodeFile(path string, settings *Settings) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", path, err)
+	}
+	return Decode(path, data, settings)
+}
+
+// Decode parses configuration data on top of the given settings, rejecting unknown keys.
+// The data is TOML when the path has the .toml extension, YAML otherwise.
+func Decode(path string, data []byte, settings *Settings) error {
+	if IsTOML(path) {
+		converted, err := TOMLToYAML(data)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %v", path, err)
+		}
+		data = converted
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(settings); err != nil && err != io.EOF {
+		return fmt.Errorf("parsing %s: %v", path, err)
+	}
+	return nil
+}
+
+// RoleModel returns the model configured for a role, empty for the backend default
+func (s *Settings) RoleModel(role string) string {
+	return s.Roles[role].Model
+}
+
+// RoleAllowedTools returns the tools a role may use without asking, nil for the backend defaults
+func (s *Settings) RoleAllowedTools(role string) []string {
+	return s.Roles[role].AllowedTools
+}
+
+// RoleTimeout returns the time limit of a role, zero for no limit
+func (s *Settings) RoleTimeout(role string) time.Duration {
+	if timeout := s.Roles[role].Timeout; timeout > 0 {
+		return time.Duration(timeout)
+	}
+	return time.Duration(s.Timeout)
+}
+
+// RoleMaxTurns returns the turn limit of a role, zero for no limit
+func (s *Settings) RoleMaxTurns(role string) int {
+	if maxTurns := s.Roles[role].MaxTurns; maxTurns > 0 {
+		return maxTurns
+	}
+	return s.MaxTurns
+}
+
+// RoleMaxCost returns the cost limit in USD of a role, zero for no limit
+func (s *Settings) RoleMaxCost(role string) float64 {
+	if maxCost := s.Roles[role].MaxCost; maxCost > 0 {
+		return maxCost
+	}
+	return s.MaxCost
+}
+
+// RoleMaxTokens returns the token limit of a role, zero for no limit
+func (s *Settings) RoleMaxTokens(role string) int64 {
+	if maxTokens := s.Roles[role].MaxTokens; maxTokens > 0 {
+		return maxTokens
+	}
+	return s.MaxTokens
+}
+
+// SectionName returns the name configured for one of the built-in sections, e.g. SolutionProposalSection.
+// Other names are returned unchanged.
+func SectionName(name string) string {
+	names := current.Sections
+	renamed := map[string]string{
+		ExplorationReportSection: names.ExplorationReport,
+		IssueExplanationSection:  names.IssueExplanation,
+		SolutionProposalSection:  names.SolutionProposal,
+		ImplementedCodeSection:   names.ImplementedCode,
+		TestReportSection:        names.TestReport,
+		CodeReviewSection:        names.CodeReview,
+		VerificationSection:      names.VerificationReport,
+	}[name]
+	if renamed != "" {
+		return renamed
+	}
+	return name
+}
+
+// Sections returns the sections of the context file, in order, with their configured names
+func Sections() []string {
+	names := []string{
+		ExplorationReportSection,
+		IssueExplanationSection,
+		SolutionProposalSection,
+		ImplementedCodeSection,
+	}
+	// The verification report only exists in projects with verification commands
+	if len(current.Verify.Commands) > 0 {
+		names = append(names, VerificationSection)
+	}
+	names = append(names, TestReportSection, CodeReviewSection)
+
+	var sections []string
+	for _, name := range names {
+		sections = append(sections, SectionName(name))
+	}
+
+	// Then the sections owned by custom roles, in the order of the role names
+	for _, role := range CustomRoles() {
+		if role.Section != "" && !containsFold(sections, role.Section) {
+			sections = append(sections, role.Section)
+		}
+	}
+	return sections
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// SectionsByKey returns the configured section names by their configuration key, e.g. "solution_proposal"
+func SectionsByKey() map[string]string {
+	return map[string]string{
+		"exploration_report":  SectionName(ExplorationReportSection),
+		"issue_explanation":   SectionName(IssueExplanationSection),
+		"solution_proposal":   SectionName(SolutionProposalSection),
+		"implemented_code":    SectionName(ImplementedCodeSection),
+		"test_report":         SectionName(TestReportSection),
+		"code_review":         SectionName(CodeReviewSection),
+		"verification_report": SectionName(VerificationSection),
+	}
+}
+
+// BaseTemplate returns the template of a new context file: the heading of every section,
+// with the configured section names
+func BaseTemplate() string {
+	var b strings.Builder
+	for i, name := range Sections() {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("# " + name + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "valid", content: "timeout: 30m\n", want: 30 * time.Minute},
+		{name: "zero", content: "timeout: 0s\n", wantErr: true},
+		{name: "negative", content: "timeout: -5m\n", wantErr: true},
+		{name: "negative role timeout", content: "roles:\n  developer:\n    timeout: -1h\n", wantErr: true},
+		{name: "zero verify timeout", content: "verify:\n  timeout: 0m\n", wantErr: true},
+		{name: "invalid", content: "timeout: soon\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var settings Settings
+			err := Decode(ProjectConfigPath, []byte(test.content), &settings)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Decode() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && time.Duration(settings.Timeout) != test.want {
+				t.Errorf("timeout = %v, want %v", time.Duration(settings.Timeout), test.want)
+			}
+		})
+	}
+}
+
+func TestValidateNegativeTimeouts(t *testing.T) {
+	negative := Duration(-time.Minute)
+	tests := []struct {
+		name     string
+		settings *Settings
+	}{
+		{"global", &Settings{Timeout: negative}},
+		{"role", &Settings{Roles: map[string]RoleSettings{"developer": {Timeout: negative}}}},
+		{"verify", &Settings{Verify: VerifySettings{Timeout: negative}}},
+		{"pipeline step", &Settings{Pipelines: map[string]Pipeline{"x": {Steps: []Step{{Role: "developer", Timeout: negative}}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Validate(test.settings); err == nil {
+				t.Error("Validate() accepted a negative timeout")
+			}
+		})
+	}
+	if err := Validate(&Settings{Timeout: Duration(time.Hour)}); err != nil {
+		t.Errorf("Validate() = %v, want a positive timeout accepted", err)
+	}
+}
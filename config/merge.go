@@ -0,0 +1,102 @@
+package config
+
+// Merge returns the settings of base overridden by the ones set in override, field by field:
+// a setting override leaves empty keeps its value from base. Roles are merged the same way,
+// so a project file tuning the timeout of a role keeps the model the user file gives it.
+// Lists, such as claude.args or verify.commands, and pipelines are replaced whole.
+func Merge(base *Settings, override *Settings) *Settings {
+	merged := *base
+	setString(&merged.Backend, override.Backend)
+	setString(&merged.BaseBranch, override.BaseBranch)
+	setString(&merged.BranchTemplate, override.BranchTemplate)
+	if override.Streaming != nil {
+		merged.Streaming = override.Streaming
+	}
+	setString(&merged.Ownership, override.Ownership)
+	if override.Retries != nil {
+		merged.Retries = override.Retries
+	}
+	if override.AddTaskTrailer != nil {
+		merged.AddTaskTrailer = override.AddTaskTrailer
+	}
+	setNumber(&merged.Timeout, override.Timeout)
+	setNumber(&merged.MaxTurns, override.MaxTurns)
+	setNumber(&merged.MaxCost, override.MaxCost)
+	setNumber(&merged.MaxTokens, override.MaxTokens)
+	setNumber(&merged.TaskBudget.MaxCost, override.TaskBudget.MaxCost)
+	setNumber(&merged.TaskBudget.MaxTokens, override.TaskBudget.MaxTokens)
+
+	setString(&merged.Claude.Binary, override.Claude.Binary)
+	setList(&merged.Claude.Args, override.Claude.Args)
+
+	merged.Roles = map[string]RoleSettings{}
+	for name, role := range base.Roles {
+		merged.Roles[name] = role
+	}
+	for name, role := range override.Roles {
+		merged.Roles[name] = mergeRole(merged.Roles[name], role)
+	}
+	if len(merged.Roles) == 0 {
+		merged.Roles = nil
+	}
+
+	sections, renamed := &merged.Sections, override.Sections
+	setString(&sections.ExplorationReport, renamed.ExplorationReport)
+	setString(&sections.IssueExplanation, renamed.IssueExplanation)
+	setString(&sections.SolutionProposal, renamed.SolutionProposal)
+	setString(&sections.ImplementedCode, renamed.ImplementedCode)
+	setString(&sections.TestReport, renamed.TestReport)
+	setString(&sections.CodeReview, renamed.CodeReview)
+	setString(&sections.VerificationReport, renamed.VerificationReport)
+
+	merged.Pipelines = map[string]Pipeline{}
+	for name, pipeline := range base.Pipelines {
+		merged.Pipelines[name] = pipeline
+	}
+	for name, pipeline := range override.Pipelines {
+		merged.Pipelines[name] = pipeline
+	}
+	if len(merged.Pipelines) == 0 {
+		merged.Pipelines = nil
+	}
+
+	setList(&merged.Verify.Commands, override.Verify.Commands)
+	setNumber(&merged.Verify.FixAttempts, override.Verify.FixAttempts)
+	setNumber(&merged.Verify.Timeout, override.Verify.Timeout)
+	return &merged
+}
+
+// mergeRole overrides the settings of a role field by field
+func mergeRole(base RoleSettings, override RoleSettings) RoleSettings {
+	merged := base
+	setString(&merged.Model, override.Model)
+	setNumber(&merged.Timeout, override.Timeout)
+	setNumber(&merged.MaxTurns, override.MaxTurns)
+	setNumber(&merged.MaxCost, override.MaxCost)
+	setNumber(&merged.MaxTokens, override.MaxTokens)
+	setList(&merged.AllowedTools, override.AllowedTools)
+	setString(&merged.Description, override.Description)
+	setString(&merged.Prompt, override.Prompt)
+	setString(&merged.Section, override.Section)
+	setList(&merged.Requires, override.Requires)
+	merged.Branch = merged.Branch || override.Branch
+	return merged
+}
+
+func setString(value *string, override string) {
+	if override != "" {
+		*value = override
+	}
+}
+
+func setNumber[T int | int64 | float64 | Duration](value *T, override T) {
+	if override != 0 {
+		*value = override
+	}
+}
+
+func setList[T any](value *[]T, override []T) {
+	if override != nil {
+		*value = override
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	yes, three := true, 3
+	full := &Settings{
+		Backend:        "fake",
+		BaseBranch:     "develop",
+		BranchTemplate: "feature/{{ .Date }}",
+		Streaming:      &yes,
+		Ownership:      "fail",
+		Retries:        &three,
+		Timeout:        Duration(time.Hour),
+		MaxTurns:       50,
+		MaxCost:        2.5,
+		MaxTokens:      100000,
+		TaskBudget:     BudgetSettings{MaxCost: 10, MaxTokens: 1000000},
+		Claude:         ClaudeSettings{Binary: "claude-dev", Args: []string{"--debug"}},
+		Roles: map[string]RoleSettings{
+			"developer": {Model: "opus", Timeout: Duration(time.Minute), MaxTurns: 10, MaxCost: 1, MaxTokens: 1000, AllowedTools: []string{"Read"}},
+			"auditor":   {Description: "Audits", Prompt: "audit.md", Section: "Audit", Requires: []string{"Implemented Code"}, Branch: true},
+		},
+		Sections:  SectionNames{ExplorationReport: "A", IssueExplanation: "B", SolutionProposal: "C", ImplementedCode: "D", TestReport: "E", CodeReview: "F", VerificationReport: "G"},
+		Pipelines: map[string]Pipeline{"docs": {Steps: []Step{{Role: "explorer"}}}},
+		Verify:    VerifySettings{Commands: []VerifyCommand{{Run: "go test ./..."}}, FixAttempts: 2, Timeout: Duration(time.Minute)},
+	}
+
+	tests := []struct {
+		name     string
+		base     *Settings
+		override *Settings
+		want     *Settings
+	}{
+		{"empty override keeps everything", full, &Settings{}, full},
+		{"override sets everything", &Settings{}, full, full},
+		{
+			name: "roles merge field by field",
+			base: &Settings{Roles: map[string]RoleSettings{
+				"developer": {Model: "opus", AllowedTools: []string{"Read"}},
+				"tester":    {Model: "haiku"},
+			}},
+			override: &Settings{Roles: map[string]RoleSettings{
+				"developer": {Timeout: Duration(10 * time.Minute), Model: "sonnet"},
+			}},
+			want: &Settings{Roles: map[string]RoleSettings{
+				"developer": {Model: "sonnet", Timeout: Duration(10 * time.Minute), AllowedTools: []string{"Read"}},
+				"tester":    {Model: "haiku"},
+			}},
+		},
+		{
+			name:     "nested structs merge field by field",
+			base:     &Settings{Claude: ClaudeSettings{Binary: "claude-dev", Args: []string{"--debug"}}, TaskBudget: BudgetSettings{MaxCost: 10}},
+			override: &Settings{Claude: ClaudeSettings{Binary: "claude"}, TaskBudget: BudgetSettings{MaxTokens: 5000}},
+			want:     &Settings{Claude: ClaudeSettings{Binary: "claude", Args: []string{"--debug"}}, TaskBudget: BudgetSettings{MaxCost: 10, MaxTokens: 5000}},
+		},
+		{
+			name:     "lists and pipelines are replaced whole",
+			base:     &Settings{Claude: ClaudeSettings{Args: []string{"--a", "--b"}}, Pipelines: map[string]Pipeline{"x": {Steps: []Step{{Role: "analyst"}, {Role: "developer"}}}, "y": {}}},
+			override: &Settings{Claude: ClaudeSettings{Args: []string{"--c"}}, Pipelines: map[string]Pipeline{"x": {Steps: []Step{{Role: "reviewer"}}}}},
+			want:     &Settings{Claude: ClaudeSettings{Args: []string{"--c"}}, Pipelines: map[string]Pipeline{"x": {Steps: []Step{{Role: "reviewer"}}}, "y": {}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Merge(test.base, test.override); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeKeepsBase(t *testing.T) {
+	base := &Settings{Roles: map[string]RoleSettings{"developer": {Model: "opus"}}}
+	Merge(base, &Settings{Roles: map[string]RoleSettings{"developer": {Model: "sonnet"}}})
+	if model := base.Roles["developer"].Model; model != "opus" {
+		t.Errorf("Merge() changed the base settings, model = %s", model)
+	}
+}
+
+func TestLoadMergesFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	chdir(t, t.TempDir())
+	writeFile(t, UserConfigPath(), "roles:\n  developer:\n    model: opus\n")
+	writeFile(t, ProjectConfigPath, "roles:\n  developer:\n    timeout: 10m\n")
+
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	developer := Current().Roles["developer"]
+	if developer.Model != "opus" || developer.Timeout != Duration(10*time.Minute) {
+		t.Errorf("roles.developer = %+v, want the model of the user file and the timeout of the project one", developer)
+	}
+
+	writeFile(t, ".astropath.toml", "backend = \"fake\"\n")
+	if err := Load(); err == nil {
+		t.Error("Load() accepted both .astropath.yaml and .astropath.toml")
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	chdir(t, t.TempDir())
+	writeFile(t, filepath.Join(filepath.Dir(UserConfigPath()), "config.toml"), "[roles.developer]\nmodel = \"opus\"\n")
+	writeFile(t, ".astropath.toml", `base_branch = "develop"
+
+[roles.developer]
+timeout = "10m"
+
+[verify]
+commands = ["go build ./...", { name = "test", run = "go test ./..." }]
+`)
+
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	settings := Current()
+	developer := settings.Roles["developer"]
+	if developer.Model != "opus" || developer.Timeout != Duration(10*time.Minute) {
+		t.Errorf("roles.developer = %+v, want the model of the user file and the timeout of the project one", developer)
+	}
+	want := []VerifyCommand{{Run: "go build ./..."}, {Name: "test", Run: "go test ./..."}}
+	if settings.BaseBranch != "develop" || !reflect.DeepEqual(settings.Verify.Commands, want) {
+		t.Errorf("base_branch = %s, verify.commands = %+v, want develop and %+v", settings.BaseBranch, settings.Verify.Commands, want)
+	}
+
+	writeFile(t, ".astropath.toml", "unknown = 1\n")
+	if err := Load(); err == nil {
+		t.Error("Load() accepted an unknown key in a TOML file")
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	const content = "base_branch: develop\nroles:\n  developer:\n    timeout: 10m\nverify:\n  commands:\n    - go vet ./...\n    - name: test\n      run: go test ./...\n"
+	converted, err := YAMLToTOML([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML, fromTOML Settings
+	if err := Decode(ProjectConfigPath, []byte(content), &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decode(".astropath.toml", converted, &fromTOML); err != nil {
+		t.Fatalf("Decode() of %q: %v", converted, err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromTOML) {
+		t.Errorf("TOML settings = %+v, want %+v", fromTOML, fromYAML)
+	}
+}
+
+func TestMergeWith(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	chdir(t, t.TempDir())
+	writeFile(t, UserConfigPath(), "roles:\n  docs:\n    prompt: docs.md\n")
+
+	// The project file alone is invalid, but the role is defined in the user file
+	edited := &Settings{Pipelines: map[string]Pipeline{"docs": {Steps: []Step{{Role: "docs"}}}}}
+	if err := Validate(edited); err == nil {
+		t.Fatal("Validate() accepted a pipeline with an unknown role")
+	}
+	if _, err := MergeWith(ProjectConfigPath, edited); err != nil {
+		t.Errorf("MergeWith() = %v, want the role of the user file to be known", err)
+	}
+
+	// Removing the prompt from the user file breaks the role
+	if _, err := MergeWith(UserConfigPath(), &Settings{Roles: map[string]RoleSettings{"docs": {}}}); err == nil {
+		t.Error("MergeWith() accepted a custom role without a prompt")
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+// writeFile creates a file and its directory
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
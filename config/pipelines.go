@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fynardo/astropath/internal/review"
+)
+
+// Branch handling of a pipeline step
+const (
+	StepBranchDefault = ""       // Pass the pipeline branch to the role, which decides how to use it
+	StepBranchCreate  = "create" // Check out the pipeline branch before the step, creating it from the base branch if needed
+	StepBranchNone    = "none"   // Run the role on the checked out branch
+)
+
+// What a pipeline does when a step fails
+const (
+	OnFailureStop     = "stop"     // Stop the pipeline with an error
+	OnFailureContinue = "continue" // Report the failure and run the next step
+	OnFailureAsk      = "ask"      // Ask the user whether to continue, stop with --no-pause
+)
+
+// Pipeline is a named list of steps run by 'astropath pipeline --name <name>'
+type Pipeline struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Steps       []Step `yaml:"steps" json:"steps"`
+}
+
+// Step runs a role as part of a pipeline
+type Step struct {
+	Name      string         `yaml:"name,omitempty" json:"name,omitempty"`             // Name shown in messages, the role by default
+	Role      string         `yaml:"role" json:"role"`                                 // Built-in or custom role
+	Branch    string         `yaml:"branch,omitempty" json:"branch,omitempty"`         // StepBranchCreate, StepBranchNone, or empty for the role default
+	Pause     *bool          `yaml:"pause,omitempty" json:"pause,omitempty"`           // Ask before running the next step, true by default
+	OnFailure string         `yaml:"on_failure,omitempty" json:"on_failure,omitempty"` // OnFailureStop (default), OnFailureContinue or OnFailureAsk
+	When      *StepCondition `yaml:"when,omitempty" json:"when,omitempty"`             // Run the step only when the condition holds
+	Timeout   Duration       `yaml:"timeout,omitempty" json:"timeout,omitempty"`       // Time limit for the agents of the step, overrides the role one
+	MaxTurns  int            `yaml:"max_turns,omitempty" json:"max_turns,omitempty"`   // Turn limit for the agents of the step, overrides the role one
+	MaxCost   float64        `yaml:"max_cost,omitempty" json:"max_cost,omitempty"`     // Cost limit in USD for the agents of the step, overrides the role one
+	MaxTokens int64          `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"` // Token limit for the agents of the step, overrides the role one
+
+	// Reviewer steps only: while the review reports major issues, run the developer to fix them
+	// and review again, up to this number of times
+	FixIterations int `yaml:"fix_iterations,omitempty" json:"fix_iterations,omitempty"`
+	// Reviewer steps only: fail the step when a review finding is at or above this severity
+	FailOn string `yaml:"fail_on,omitempty" json:"fail_on,omitempty"`
+}
+
+// StepCondition decides whether a step runs. All the given fields must hold.
+type StepCondition struct {
+	Var           string `yaml:"var,omitempty" json:"var,omitempty"`                       // --var <name>=<value> was given with a value other than "false"
+	SectionFilled string `yaml:"section_filled,omitempty" json:"section_filled,omitempty"` // The section of the context file has content
+	SectionEmpty  string `yaml:"section_empty,omitempty" json:"section_empty,omitempty"`   // The section is missing, empty or a template placeholder
+}
+
+// Title returns the name of the step
+func (s Step) Title() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Role
+}
+
+// Pauses reports whether the pipeline asks the user before running the next step
+func (s Step) Pauses() bool {
+	return s.Pause == nil || *s.Pause
+}
+
+// GetPipeline returns the pipeline with the given name from the configuration
+func GetPipeline(name string) (Pipeline, bool) {
+	pipeline, ok := current.Pipelines[name]
+	return pipeline, ok
+}
+
+// PipelineNames returns the names of the pipelines of the configuration, sorted
+func PipelineNames() []string {
+	names := make([]string, 0, len(current.Pipelines))
+	for name := range current.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validatePipelines checks that the steps use known roles and valid options
+func validatePipelines(settings *Settings) error {
+	for name, pipeline := range settings.Pipelines {
+		if len(pipeline.Steps) == 0 {
+			return fmt.Errorf("pipelines.%s: no steps", name)
+		}
+
+		seen := map[string]bool{}
+		for i, step := range pipeline.Steps {
+			where := fmt.Sprintf("pipelines.%s.steps[%d]", name, i)
+			if step.Role == "" {
+				return fmt.Errorf("%s: missing role", where)
+			}
+			if _, custom := settings.Roles[step.Role]; !isBuiltinRole(step.Role) && !custom {
+				return fmt.Errorf("%s: unknown role '%s'", where, step.Role)
+			}
+			switch step.Branch {
+			case StepBranchDefault, StepBranchCreate, StepBranchNone:
+			default:
+				return fmt.Errorf("%s: invalid branch '%s' (available: %s, %s)", where, step.Branch, StepBranchCreate, StepBranchNone)
+			}
+			switch step.OnFailure {
+			case "", OnFailureStop, OnFailureContinue, OnFailureAsk:
+			default:
+				return fmt.Errorf("%s: invalid on_failure '%s' (available: %s, %s, %s)", where, step.OnFailure, OnFailureStop, OnFailureContinue, OnFailureAsk)
+			}
+			if step.MaxTurns < 0 || step.MaxCost < 0 || step.MaxTokens < 0 || step.Timeout < 0 {
+				return fmt.Errorf("%s: timeout, max_turns, max_cost and max_tokens can't be negative", where)
+			}
+			if step.FixIterations < 0 || step.FixIterations > 0 && step.Role != "reviewer" {
+				return fmt.Errorf("%s: fix_iterations must be a positive number, and only applies to reviewer steps", where)
+			}
+			if step.FailOn != "" && (step.Role != "reviewer" || !review.ValidSeverity(step.FailOn)) {
+				return fmt.Errorf("%s: fail_on must be one of %s, and only applies to reviewer steps", where, strings.Join(review.Severities(), ", "))
+			}
+			if seen[step.Title()] {
+				return fmt.Errorf("%s: duplicated step name '%s', set a different name", where, step.Title())
+			}
+			seen[step.Title()] = true
+		}
+	}
+	return nil
+}
@@ -1,5 +1,11 @@
 package config
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
 
 // PromptType represents different types of prompts available
 type PromptType string
@@ -10,10 +16,42 @@ const (
 	ReviewerPromptType PromptType = "reviewer"
 	AnalystPromptType PromptType = "analyst"
 	DeveloperPromptType PromptType = "developer"
+	TesterPromptType PromptType = "tester"
 )
 
 
-// getPrompt returns the appropriate prompt based on the prompt type
+// PromptsDir holds the prompt overrides, one <prompt type>.md file per role
+const PromptsDir = AstropathDir + "/prompts"
+
+// BuiltinPromptSource is the source reported for prompts without an override file
+const BuiltinPromptSource = "built-in"
+
+// PromptTypes returns the prompts that can be overridden
+func PromptTypes() []PromptType {
+	return []PromptType{ExplorerPromptType, AnalystPromptType, DeveloperPromptType, TesterPromptType, ReviewerPromptType}
+}
+
+// PromptPath returns the override file of a prompt
+func PromptPath(promptType PromptType) string {
+	return filepath.Join(PromptsDir, string(promptType)+".md")
+}
+
+// LoadPrompt returns the prompt template of a role: the override file in PromptsDir if there is one,
+// the built-in prompt otherwise. The second value is where the prompt comes from.
+func LoadPrompt(promptType PromptType) (string, string, error) {
+	path := PromptPath(promptType)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return GetPrompt(promptType), BuiltinPromptSource, nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("reading %s: %v", path, err)
+	}
+	return string(content), path, nil
+}
+
+// getPrompt returns the appropriate prompt based on the prompt type.
+// Prompts refer to the sections of the context file with {{ section "<default name>" }}, so renamed sections are followed
 func GetPrompt(promptType PromptType) string {
 	switch promptType {
 	case AnalystPromptType:
@@ -24,6 +62,8 @@ func GetPrompt(promptType PromptType) string {
 		return ExplorerPrompt
 	case ReviewerPromptType:
 		return ReviewerPrompt
+	case TesterPromptType:
+		return TesterPrompt
 	default:
 		return DefaultPrompt
 	}
@@ -33,13 +73,13 @@ func GetPrompt(promptType PromptType) string {
 const basePrompt = `You are a helpful AI assistant. Please help the user with their software engineering tasks.
 Focus on providing clear, actionable solutions and follow best practices.
 
-- Your main way of communication is a Markdown file called ./ASTROPATH.md
-- Always update the file ./ASTROPATH.md with your feedback, but don't overwrite it from scratch.
+- Your main way of communication is a Markdown file called {{ .TaskFile }}
+- Always update the file {{ .TaskFile }} with your feedback, but don't overwrite it from scratch.
 - You are going to edit a specific section of the file. It is Markdown, so identify sections
 as blocks that start with a '#'
 - Add your text inside that section. The specific name of the section will be provided in the following paragraph as part of your task description
 - You are allowed to clear the section you are going to write if you need it.
-- You can always read any section or the whole ./ASTROPATH.md file to gather more context, especially 'Exploration Report' section.
+- You can always read any section or the whole {{ .TaskFile }} file to gather more context, especially '{{ section "Exploration Report" }}' section.
 ` + "\n"
 
 const DefaultPrompt = basePrompt
@@ -51,17 +91,17 @@ const ExplorerPrompt = basePrompt + "\n" + `Your next task is to explore a proje
 	3. What are the main components of the project
 	Keep it short, don't think too much, just do a basic exploration.
 
-	- Don't forget to edit the ./ASTROPATH.md file with your findings, use the section called 'Exploration Report'.
+	- Don't forget to edit the {{ .TaskFile }} file with your findings, use the section called '{{ section "Exploration Report" }}'.
 `
 
 const ReviewerPrompt = basePrompt + "\n" + `For your next task you are going to be a code reviewer AI assistant.
 	You are going to review the udpates to the code in a branch, probably part of a pull request, so you will:
-	1. Get a diff of the branch compared to main: 'git diff main {{ .BranchName }}'
-	2. Check both 'Issue Explanation' and 'Solution Proposal' sections in the ./ASTROPATH.md file.
+	1. Get a diff of the changes to review: 'git diff {{ .DiffRange }}'
+	2. Check both '{{ section "Issue Explanation" }}' and '{{ section "Solution Proposal" }}' sections in the {{ .TaskFile }} file.
 	3. Review the code update and provide feedback.
 
   Good feedback is composed of:
-  - Major issues: Like potential logic issues or if the updated code missmatchs the intention described in other sections of ./ASTROPATH.md file.
+  - Major issues: Like potential logic issues or if the updated code missmatchs the intention described in other sections of {{ .TaskFile }} file.
 	- Minor issues: Like typing mistakes or formatting issues
 	- Suggestions: Like adding new packages to simplify things
 
@@ -69,11 +109,22 @@ const ReviewerPrompt = basePrompt + "\n" + `For your next task you are going to
 	- Major issues are the most important, so think more here
 	- Minor issues and suggestions are less important, don't think too much here.
 
-	Don't forget to add your findings to the ./ASTROPATH.md file, your section is called 'Code Review'.
+	Write each group under its own heading ('## Major Issues', '## Minor Issues' and '## Suggestions') as a bullet points list,
+	and write 'None' under a heading when there is nothing to report. If the section already contains a previous review, replace it.
+
+	At the end of the section, list every finding again in a fenced code block opened with ` + "'```json findings'" + `, so tools can read them.
+	It is a JSON array with one object per finding:
+	  {"severity": "major", "category": "correctness", "file": "path/to/file.go", "line": 42, "message": "What is wrong", "suggestion": "How to fix it"}
+	- "severity" is "major", "minor" or "suggestion", matching the group of the finding
+	- "category" is one of "correctness", "security", "performance", "maintainability", "style", "tests", "docs" or "general"
+	- "file" is relative to the repository root, and "line" is 0 when the finding isn't about a specific line
+	- Write an empty array '[]' when there is nothing to report
+
+	Don't forget to add your findings to the {{ .TaskFile }} file, your section is called '{{ section "Code Review" }}'.
 `
 
 const AnalystPrompt = basePrompt + "\n" + `For your next task you are going to be a software analyst AI assistant.
-	You are going to review an Issue detailed in the ./ASTROPATH.md file, under the 'Issue Explanation' section.
+	You are going to review an Issue detailed in the {{ .TaskFile }} file, under the '{{ section "Issue Explanation" }}' section.
 	Your task is to propose a solution for that Issue that consists of:
 	1. A list of bullet points explaining what you want to achieve
 	2. A TO-DO list explaining how you would do it
@@ -81,25 +132,66 @@ const AnalystPrompt = basePrompt + "\n" + `For your next task you are going to b
 	Always remember that you are an analyst, you don't write code, your task it to
 	propose a high-level solution to the problem that a coder can implement.
 
-	Don't forget to add your findings to the ./ASTROPATH.md file, your section is called 'Solution Proposal'.`
+	Don't forget to add your findings to the {{ .TaskFile }} file, your section is called '{{ section "Solution Proposal" }}'.`
 
 
 const DeveloperPrompt = basePrompt + "\n" + `For your next task you are going to be a software developer AI assistant.
-	You are going to review the ./ASTROPATH.md file, which contains:
-	- An issue explained in the 'Issue Explanation' section
-	- A proposed solution in the 'Solution Proposal' section
+	You are going to review the {{ .TaskFile }} file, which contains:
+	- An issue explained in the '{{ section "Issue Explanation" }}' section
+	- A proposed solution in the '{{ section "Solution Proposal" }}' section
 	-	**important**: If any of these sections is empty, just report it and exit. Don't try to code anything that is not clearly
-	detailed in the ./ASTROPATH.md file.
+	detailed in the {{ .TaskFile }} file.
 
-	As a developer assistant your task is to implement the solution proposed in the 'Solution Proposal' section.
+	As a developer assistant your task is to implement the solution proposed in the '{{ section "Solution Proposal" }}' section.
 	For that you will:
-	1. Checkout to a new git branch ( {{ .BranchName }} ). Never update main directly.
-	2. Implement the solution as stated in the 'Solution Proposal'
+	1. Work on the git branch '{{ .BranchName }}', Astropath already checked it out for you. Don't switch branches and never update {{ .BaseBranch }} directly.
+	2. Implement the solution as stated in the '{{ section "Solution Proposal" }}'
 	3. Generate a summary bullet points list containing the most relevant changes.
-	4. Commit your changes and the new files that you created (if any). The commit message will be the summary and a the following line "Generated with Claude Code / Astropath" to grant recognition to the AI framework.
-	5. Update the ./ASTROPATH.md file with the summary bullet points list in the 'Implemented Code' section.
-	6. Update the ./ASTROPATH.md file with a list of the files you modified or created.
+	4. Commit your changes and the new files that you created (if any). The commit message will be the summary and a the following line "Generated with Claude Code / Astropath" to grant recognition to the AI framework.{{ if .TaskID }}
+	Add the git trailer "` + TaskTrailer + `: {{ .TaskID }}" to the commit message, with git commit --trailer "` + TaskTrailer + `: {{ .TaskID }}", so the commit can be traced back to its task.{{ end }}
+	5. Update the {{ .TaskFile }} file with the summary bullet points list in the '{{ section "Implemented Code" }}' section.
+	6. Update the {{ .TaskFile }} file with a list of the files you modified or created.
 
 	Do not try to push the branch to the remote repository, just commit it locally as it will need more reviews before pushing it.
-	Remember to update the ./ASTROPATH.md file within the 'Implemented code' section.
+	Remember to update the {{ .TaskFile }} file within the '{{ section "Implemented Code" }}' section.
+`
+
+const TesterPrompt = basePrompt + "\n" + `For your next task you are going to be a software tester AI assistant.
+	You are going to test the changes implemented in a git branch, so you will:
+	1. Work on the git branch '{{ .BranchName }}', Astropath already checked it out for you. Don't switch branches and never update {{ .BaseBranch }} directly.
+	2. Check the '{{ section "Solution Proposal" }}' and '{{ section "Implemented Code" }}' sections in the {{ .TaskFile }} file, and get a diff of the branch compared to {{ .BaseBranch }}: 'git diff {{ .BaseBranch }}...{{ .BranchName }}'
+	3. Run the existing test suite and, if the project supports it, measure the coverage before adding any test.
+	4. Write new tests or extend the existing ones so the changed code is covered. Follow the testing conventions already used in the project.
+	5. Run the test suite again and measure the coverage.
+	6. Commit the tests you wrote. The commit message will be a short summary and the following line "Generated with Claude Code / Astropath".{{ if .TaskID }}
+	Add the git trailer "` + TaskTrailer + `: {{ .TaskID }}" to the commit message, with git commit --trailer "` + TaskTrailer + `: {{ .TaskID }}", so the commit can be traced back to its task.{{ end }}
+
+	Do not fix the implemented code yourself, if a test fails because of a bug, report it.
+
+	Don't forget to add your findings to the {{ .TaskFile }} file, your section is called '{{ section "Test Report" }}'. It must contain:
+	- The tests you added or modified
+	- The test results: how many passed and failed, and the failure details if any
+	- The coverage delta (before -> after), or why it could not be measured
+`
+
+// ReviewFixPrompt is appended to the developer prompt to fix the major issues found by the reviewer
+const ReviewFixPrompt = `IMPORTANT: the solution is already implemented in the branch '{{ .BranchName }}', and a code review found major issues.
+	Read the 'Major Issues' of the '{{ section "Code Review" }}' section in the {{ .TaskFile }} file and fix all of them.
+	Commit the fixes on top of the existing commits, and update the '{{ section "Implemented Code" }}' section with what you changed.
+	Don't modify the '{{ section "Code Review" }}' section, the reviewer will check your fixes.
+`
+
+// CorrectionPrompt is appended to the prompt of a role whose output failed the checks, before retrying it
+const CorrectionPrompt = `IMPORTANT: a previous attempt at this task finished without completing it.
+	The '{{ .Section }}' section of the {{ .TaskFile }} file has these problems:
+{{ range .Problems }}	- {{ . }}
+{{ end }}
+	Fix them now by updating the '{{ .Section }}' section as described above. Don't modify any other section.
+`
+
+// VerifyFixPrompt is appended to the developer prompt to fix the verification commands that failed
+const VerifyFixPrompt = `IMPORTANT: the solution is already implemented in the branch '{{ .BranchName }}', but some verification commands failed on it.
+	Read the '{{ section "Verification Report" }}' section in the {{ .TaskFile }} file, which has the commands and their output, and fix the failures.
+	Commit the fixes on top of the existing commits, and update the '{{ section "Implemented Code" }}' section with what you changed.
+	Don't modify the '{{ section "Verification Report" }}' section, Astropath will run the commands again after your fixes.
 `
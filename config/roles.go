@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Role describes an agent role and how it uses the sections of the context file
+type Role struct {
+	Name        string
+	Description string
+	PromptType  PromptType    // Prompt of a built-in role
+	PromptFile  string        // Prompt template file of a custom role
+	Section     string        // Section the role owns, the only one it may modify
+	Requires    []string      // Sections that must be filled in before the role runs
+	Checks      []OutputCheck // Checks on the owned section after the role runs, besides being filled in
+	Branch      bool          // The role works on a feature branch (custom roles)
+}
+
+// OutputCheck verifies the content a role wrote to its section
+type OutputCheck struct {
+	Description string // What the section must contain, e.g. "a TO-DO list"
+	Pattern     string // Regular expression the section content must match
+}
+
+// Patterns used by the built-in output checks
+const (
+	todoListPattern = `(?im)(^\s*[-*+]\s+\[[ xX]\]\s+)|(to-?do(.|\n)*^\s*([-*+]|\d+[.)])\s+\S)`
+	fileListPattern = `(?m)^\s*([-*+]|\d+[.)])\s+.*([\w.-]+/[\w./-]+|[\w-]+\.[A-Za-z0-9]{1,8}\b)`
+	bulletsPattern  = `(?m)^\s*([-*+]|\d+[.)])\s+\S`
+)
+
+// builtinRoles are the roles with a dedicated command
+var builtinRoles = []Role{
+	{Name: "explorer", Description: "Explores the project and documents it", PromptType: ExplorerPromptType, Section: ExplorationReportSection},
+	{Name: "analyst", Description: "Proposes a solution for the issue", PromptType: AnalystPromptType, Section: SolutionProposalSection,
+		Requires: []string{IssueExplanationSection},
+		Checks: []OutputCheck{
+			{Description: "a TO-DO list", Pattern: todoListPattern},
+		}},
+	{Name: "developer", Description: "Implements the proposed solution on a branch", PromptType: DeveloperPromptType, Section: ImplementedCodeSection,
+		Requires: []string{IssueExplanationSection, SolutionProposalSection},
+		Checks: []OutputCheck{
+			{Description: "a summary bullet points list", Pattern: bulletsPattern},
+			{Description: "a list of the files modified or created", Pattern: fileListPattern},
+		}},
+	{Name: "tester", Description: "Writes and runs tests for the branch", PromptType: TesterPromptType, Section: TestReportSection,
+		Requires: []string{SolutionProposalSection, ImplementedCodeSection}},
+	{Name: "reviewer", Description: "Reviews the changes of the branch", PromptType: ReviewerPromptType, Section: CodeReviewSection,
+		Requires: []string{IssueExplanationSection, SolutionProposalSection}},
+}
+
+// GetRole returns the built-in or custom role with the given name, using the section names of the configuration
+func GetRole(name string) (Role, bool) {
+	for _, role := range builtinRoles {
+		if role.Name == name {
+			return withSectionNames(role), true
+		}
+	}
+	for _, role := range CustomRoles() {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+// Roles returns the built-in roles followed by the custom ones
+func Roles() []Role {
+	var roles []Role
+	for _, role := range builtinRoles {
+		roles = append(roles, withSectionNames(role))
+	}
+	return append(roles, CustomRoles()...)
+}
+
+// CustomRoles returns the roles declared in the configuration files, sorted by name
+func CustomRoles() []Role {
+	var names []string
+	for name := range current.Roles {
+		if !isBuiltinRole(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	roles := make([]Role, 0, len(names))
+	for _, name := range names {
+		settings := current.Roles[name]
+		roles = append(roles, withSectionNames(Role{
+			Name:        name,
+			Description: settings.Description,
+			PromptFile:  settings.Prompt,
+			Section:     settings.Section,
+			Requires:    settings.Requires,
+			Branch:      settings.Branch,
+		}))
+	}
+	return roles
+}
+
+func isBuiltinRole(name string) bool {
+	for _, role := range builtinRoles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withSectionNames maps the sections of a role to their configured names
+func withSectionNames(role Role) Role {
+	role.Section = SectionName(role.Section)
+	requires := make([]string, 0, len(role.Requires))
+	for _, section := range role.Requires {
+		requires = append(requires, SectionName(section))
+	}
+	role.Requires = requires
+	return role
+}
+
+// LoadRolePrompt returns the prompt template of a role and where it comes from.
+// The prompt file of a custom role is appended to the base prompt shared by all the roles.
+func LoadRolePrompt(role Role) (string, string, error) {
+	if role.PromptFile == "" {
+		return LoadPrompt(role.PromptType)
+	}
+	content, err := os.ReadFile(role.PromptFile)
+	if err != nil {
+		return "", "", fmt.Errorf("reading the prompt of the %s role: %v", role.Name, err)
+	}
+	return basePrompt + "\n" + string(content), role.PromptFile, nil
+}
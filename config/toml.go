@@ -0,0 +1,41 @@
+package config
+
+import (
+	"bytes"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// TOML configuration files are converted to YAML, so both formats share the same keys, checks and
+// custom types such as Duration. Comments of TOML files are not kept by the conversions.
+
+// TOMLToYAML converts the content of a TOML configuration file to YAML
+func TOMLToYAML(data []byte) ([]byte, error) {
+	var values map[string]interface{}
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return yaml.Marshal(values)
+}
+
+// YAMLToTOML converts YAML configuration data to the content of a TOML configuration file
+func YAMLToTOML(data []byte) ([]byte, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var buff bytes.Buffer
+	encoder := toml.NewEncoder(&buff)
+	encoder.Indent = ""
+	if err := encoder.Encode(values); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
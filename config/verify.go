@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VerifySettings configures the commands Astropath runs on the branch after the developer, e.g. to build and test it
+type VerifySettings struct {
+	Commands    []VerifyCommand `yaml:"commands,omitempty"`
+	FixAttempts int             `yaml:"fix_attempts,omitempty"` // Times the developer is asked to fix failing commands, 0 to just fail
+	Timeout     Duration        `yaml:"timeout,omitempty"`      // Time limit for each command
+}
+
+// VerifyCommand is a shell command that must succeed for the work of the developer to be accepted
+type VerifyCommand struct {
+	Name string `yaml:"name,omitempty"` // Name shown in messages, the command by default
+	Run  string `yaml:"run"`
+}
+
+// UnmarshalYAML also accepts a plain string, the command to run
+func (c *VerifyCommand) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Run = value.Value
+		return nil
+	}
+	type plain VerifyCommand
+	return value.Decode((*plain)(c))
+}
+
+// Title returns the name of the command
+func (c VerifyCommand) Title() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Run
+}
+
+// validateVerify checks that every verification command has something to run
+func validateVerify(settings *Settings) error {
+	for i, command := range settings.Verify.Commands {
+		if command.Run == "" {
+			return fmt.Errorf("verify.commands[%d]: missing command to run", i)
+		}
+	}
+	if settings.Verify.FixAttempts < 0 || settings.Verify.Timeout < 0 {
+		return fmt.Errorf("verify.fix_attempts and verify.timeout can't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package claude
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// DefaultBackend is the backend used when none is selected.
+const DefaultBackend = "claude"
+
+// Backend launches local agent processes for a given prompt.
+// The Claude Code CLI is the default implementation, other local agent CLIs
+// can be plugged in by registering a new Backend.
+type Backend interface {
+	// Name returns the name the backend was registered with
+	Name() string
+	// Start launches a new agent for the request
+	Start(req Request) (Agent, error)
+}
+
+// Request describes the agent to launch.
+type Request struct {
+	Prompt    string
+	Resume    string        // Session to continue with the prompt, empty to start a new one
+	Model     string        // Model to use, empty for the backend default
+	Timeout   time.Duration // The agent is stopped after this time, zero for no limit
+	MaxTurns  int           // The agent is stopped after this number of turns, zero for no limit
+	MaxCost   float64       // The agent is stopped when its cost in USD goes over this, zero for no limit
+	MaxTokens int64         // The agent is stopped when its tokens (see Usage.Spent) go over this, zero for no limit
+
+	AllowedTools []string // Tools the agent may use without asking, empty for the backend defaults
+}
+
+// Agent is a running agent process started by a Backend.
+type Agent interface {
+	// Output returns the lines written by the agent. The channel is closed when the output ends.
+	Output() <-chan string
+	// Wait blocks until the agent exits and its output has been consumed.
+	Wait() error
+	// Signal asks the agent to stop, e.g. forwarding the SIGINT received by Astropath.
+	Signal(sig os.Signal) error
+	// Cancel kills the agent.
+	Cancel() error
+}
+
+var backends = map[string]func() Backend{}
+
+// RegisterBackend makes a backend available under the given name.
+func RegisterBackend(name string, factory func() Backend) {
+	backends[name] = factory
+}
+
+// NewBackend returns the backend registered under the given name.
+// An empty name selects the ASTROPATH_BACKEND environment variable, or DefaultBackend.
+func NewBackend(name string) (Backend, error) {
+	if name == "" {
+		name = os.Getenv("ASTROPATH_BACKEND")
+	}
+	if name == "" {
+		name = DefaultBackend
+	}
+
+	factory, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent backend %q (available: %v)", name, Backends())
+	}
+	return factory(), nil
+}
+
+// Backends returns the names of all registered backends.
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,136 +1,378 @@
 package claude
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fynardo/astropath/internal/proc"
 )
 
 // Package claude provides a way for interacting with Claude Code.
 
 
+// PromptParams holds the template parameters shared by all the prompts
 type PromptParams struct {
+	TaskID     string            // Id of the task the agent works on, empty for the default ASTROPATH.md
+	TaskFile   string            // Context file the agent reads and writes, e.g. ./ASTROPATH.md
+	BranchName string            // Branch the agent works on
+	BaseBranch string            // Branch agents never update directly, changes are compared to it
+	RepoName   string            // Name of the repository directory
+	Sections   map[string]string // Section names by configuration key, e.g. {{ .Sections.solution_proposal }}
+	Vars       map[string]string // User variables given with --var key=value, e.g. {{ .Vars.ticket }}
 }
 
 type ReviewerParams struct {
-	BranchName string // Name of the pull request to review
+	PromptParams
+	DiffRange string // Commit range passed to 'git diff'
+}
+
+func init() {
+	RegisterBackend("claude", func() Backend { return &CodeBackend{Binary: "claude"} })
+}
+
+// CodeBackend runs agents through the Claude Code CLI ('claude -p').
+type CodeBackend struct {
+	Binary string   // Path or name of the claude executable
+	Args   []string // Extra arguments appended to every invocation
+}
+
+func (b *CodeBackend) Name() string {
+	return "claude"
 }
 
+func (b *CodeBackend) Start(req Request) (Agent, error) {
+	args := []string{"--verbose", "-p", req.Prompt, "--output-format", "stream-json"}
+	if req.Resume != "" {
+		args = append(args, "--resume", req.Resume)
+	}
+	if req.Model != "" {
+		args = append(args, "--model", req.Model)
+	}
+	if req.MaxTurns > 0 {
+		args = append(args, "--max-turns", strconv.Itoa(req.MaxTurns))
+	}
+	if len(req.AllowedTools) > 0 {
+		args = append(args, "--allowedTools", strings.Join(req.AllowedTools, ","))
+	}
+	args = append(args, b.Args...)
+	return StartProcess(b.Binary, args...)
+}
 
-// streamReader reads from a pipe and processes stream-json output
-func streamReader(reader io.Reader, done chan<- error) {
+
+// Result is the outcome of an agent run, as reported by its stream-json events.
+type Result struct {
+	SessionID string
+	Model     string
+	Usage     Usage
+	CostUSD   float64
+	Estimated bool // CostUSD is estimated from the tokens, the agent was stopped before reporting it
+	NumTurns  int
+	IsError   bool          // The agent reported an error result
+	Duration  time.Duration // Wall time of the run
+	Err       error         // Error running the agent process
+
+	lastMessageID   string
+	turns           int     // Assistant messages seen so far
+	estimatedCost   float64 // Cost of the assistant messages seen so far
+	costReported    bool    // The result event reported the cost of the run
+	maxTurnsReached bool    // The agent stopped itself at its turn limit
+}
+
+// resultMaxTurns is the subtype of the result event of an agent that reached its turn limit
+const resultMaxTurns = "error_max_turns"
+
+// LimitError is the error of an agent stopped because it reached one of its limits.
+// Only the field of the limit that was reached is set.
+type LimitError struct {
+	Timeout   time.Duration
+	MaxTurns  int
+	MaxCost   float64
+	MaxTokens int64
+}
+
+func (e *LimitError) Error() string {
+	switch {
+	case e.Timeout > 0:
+		return fmt.Sprintf("timed out after %s", e.Timeout)
+	case e.MaxCost > 0:
+		return fmt.Sprintf("went over the cost limit of $%.2f", e.MaxCost)
+	case e.MaxTokens > 0:
+		return fmt.Sprintf("went over the limit of %d tokens", e.MaxTokens)
+	default:
+		return fmt.Sprintf("reached the limit of %d turns", e.MaxTurns)
+	}
+}
+
+// cost returns the cost of the run in USD: the one reported by the agent,
+// or an estimate from the tokens of its messages until it reports it.
+func (r *Result) cost() float64 {
+	if r.costReported {
+		return r.CostUSD
+	}
+	return r.estimatedCost
+}
+
+// exceeded returns the limit of the request the run went over, nil when it is within them
+func (r *Result) exceeded(req Request) error {
+	switch {
+	case req.MaxTurns > 0 && r.turns > req.MaxTurns:
+		return &LimitError{MaxTurns: req.MaxTurns}
+	case req.MaxCost > 0 && r.cost() > req.MaxCost:
+		return &LimitError{MaxCost: req.MaxCost}
+	case req.MaxTokens > 0 && r.Usage.Spent() > req.MaxTokens:
+		return &LimitError{MaxTokens: req.MaxTokens}
+	}
+	return nil
+}
+
+// Observe updates the result with the information carried by an event.
+// Usage is accumulated from assistant messages until the final result event reports the totals.
+func (r *Result) Observe(event *Event) {
+	if event.SessionID != "" {
+		r.SessionID = event.SessionID
+	}
+
+	switch event.Type {
+	case SystemEvent:
+		if event.IsInit() {
+			r.Model = event.Model
+		}
+	case AssistantEvent:
+		// Messages are streamed once per content block, count each message once.
+		// Messages without an ID can't be matched, each one is a turn.
+		if event.Message != nil && (event.Message.ID == "" || event.Message.ID != r.lastMessageID) {
+			r.lastMessageID = event.Message.ID
+			r.turns++
+			if event.Message.Usage != nil {
+				r.Usage.Add(*event.Message.Usage)
+				model := event.Message.Model
+				if model == "" {
+					model = r.Model
+				}
+				r.estimatedCost += EstimateCost(model, *event.Message.Usage)
+			}
+		}
+	case ResultEvent:
+		r.NumTurns = event.NumTurns
+		r.CostUSD = event.TotalCostUSD
+		r.costReported = true
+		r.IsError = event.IsError
+		r.maxTurnsReached = event.Subtype == resultMaxTurns
+		if event.Usage != nil {
+			r.Usage = *event.Usage
+		}
+	}
+}
+
+// streamReader parses stream-json output lines into the result.
+// Events are rendered in a human readable way when render is set, otherwise lines are printed as-is.
+// When the agent goes over a limit of the request, the limit is sent on exceeded.
+func streamReader(lines <-chan string, render bool, result *Result, req Request, exceeded chan<- error, done chan<- error) {
 	defer close(done)
 	
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := scanner.Text()
-		
-		// Try to parse as JSON to extract content
-		var jsonData map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &jsonData); err == nil {
-			// Extract and print content if it exists
-			if msgType, ok := jsonData["type"].(string); ok && msgType == "user" {
-				fmt.Println(jsonData["message"])
-			}
-		} else {
-			// If not JSON, just print the line as-is
+	var parser Parser
+	signaled := false
+	for line := range lines {
+		event, err := parser.Parse(line)
+		if discarded := parser.Discarded(); discarded != "" && render {
+			// The line didn't complete the buffered event, show what was buffered as plain text
+			fmt.Println(discarded)
+		}
+		if !render || err != nil {
+			// If not rendering or not a stream-json event, just print the line as-is
 			fmt.Println(line)
 		}
+		if err == nil && event != nil {
+			result.Observe(event)
+			if render {
+				printEvent(event)
+			}
+			// The final result comes when the agent is already done, there is nothing left to stop
+			if event.Type != ResultEvent && !signaled {
+				if limit := result.exceeded(req); limit != nil {
+					signaled = true
+					exceeded <- limit
+				}
+			}
+		}
 	}
-	
-	if err := scanner.Err(); err != nil {
-		done <- fmt.Errorf("error reading stream: %v", err)
-		return
+
+	if pending := parser.Flush(); pending != "" && render {
+		fmt.Println(pending)
 	}
 	
 	done <- nil
 }
 
-// RunAgent spawns a Claude agent using the 'claude -p' command with the specified prompt type.
-// It returns a channel that will receive a signal when the agent finishes.
-func RunAgent(prompt string) <-chan error {
-	done := make(chan error, 1)
-	
-	go func() {
-		fmt.Println("Starting Claude agent...")
-		fmt.Println("Using prompt:\n=====\n", prompt)
-		fmt.Println("=====")
-
-		cmd := exec.Command("claude", "--verbose", "-p", prompt, "--output-format", "stream-json")
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		
-		err := cmd.Run()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error running Claude agent: %v\n", err)
-		} else {
-			fmt.Println("Claude agent finished.")
+// printEvent renders a stream-json event in a human readable way
+func printEvent(event *Event) {
+	switch event.Type {
+	case SystemEvent:
+		if event.IsInit() {
+			fmt.Printf("[session %s | model %s]\n", event.SessionID, event.Model)
 		}
-		
-		done <- err
-		close(done)
-	}()
-	
-	return done
+	case AssistantEvent, UserEvent:
+		if event.Message == nil {
+			return
+		}
+		for _, block := range event.Message.Content {
+			switch block.Type {
+			case TextBlock:
+				fmt.Println(block.Text)
+			case ToolUseBlock:
+				fmt.Printf("-> %s %s\n", block.Name, truncate(string(block.Input), 200))
+			case ToolResultBlock:
+				status := "<-"
+				if block.IsError {
+					status = "<- error:"
+				}
+				fmt.Printf("%s %s\n", status, truncate(block.ResultText(), 200))
+			}
+		}
+	case ResultEvent:
+		fmt.Printf("[%s | %d turns | %.1fs | $%.4f]\n", event.Subtype, event.NumTurns, float64(event.DurationMs)/1000, event.TotalCostUSD)
+		if event.Usage != nil {
+			fmt.Printf("[tokens in %d | out %d | cache write %d | cache read %d]\n",
+				event.Usage.InputTokens, event.Usage.OutputTokens,
+				event.Usage.CacheCreationInputTokens, event.Usage.CacheReadInputTokens)
+		}
+	}
 }
 
-// RunAgentWithStreaming spawns a Claude agent with streaming output.
-// It returns a channel that will receive an error when the agent finishes.
-func RunAgentWithStreaming(prompt string) <-chan error {
-	done := make(chan error, 1)
+// truncate shortens a text to a single line of at most n characters
+func truncate(text string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return string(runes)
+}
+
+// RunAgent spawns an agent from the given backend for the request and prints its raw output.
+// It returns a channel that will receive the result when the agent finishes.
+// When the context is cancelled, the agent is signaled and killed if it doesn't exit within proc.GracePeriod.
+func RunAgent(ctx context.Context, backend Backend, req Request) <-chan Result {
+	return runAgent(ctx, backend, req, false)
+}
+
+// RunAgentWithStreaming spawns an agent from the given backend with streaming output.
+// It returns a channel that will receive the result when the agent finishes.
+func RunAgentWithStreaming(ctx context.Context, backend Backend, req Request) <-chan Result {
+	return runAgent(ctx, backend, req, true)
+}
+
+// watchAgent stops the agent when the context is cancelled or a limit is reached, and sends the reason
+// on stopped. It returns when the agent is stopped or finished is closed.
+func watchAgent(ctx context.Context, agent Agent, req Request, exceeded <-chan error, stopped chan<- error, finished <-chan struct{}) {
+	var expired <-chan time.Time
+	if req.Timeout > 0 {
+		timer := time.NewTimer(req.Timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var sig os.Signal = syscall.SIGTERM
+	select {
+	case <-finished:
+		return
+	case <-expired:
+		stopped <- &LimitError{Timeout: req.Timeout}
+	case limit := <-exceeded:
+		stopped <- limit
+	case <-ctx.Done():
+		stopped <- context.Cause(ctx)
+		sig = proc.Signal(ctx)
+	}
+
+	// Let the agent exit cleanly, then kill it
+	fmt.Fprintf(os.Stderr, "Stopping the agent, it is killed if it doesn't exit within %s...\n", proc.GracePeriod)
+	agent.Signal(sig)
+	select {
+	case <-finished:
+	case <-time.After(proc.GracePeriod):
+		agent.Cancel()
+	}
+}
+
+func runAgent(ctx context.Context, backend Backend, req Request, streaming bool) <-chan Result {
+	done := make(chan Result, 1)
 	
 	go func() {
 		defer close(done)
 		
-		fmt.Println("Starting Claude agent with streaming...")
-		fmt.Println("Using prompt:\n=====\n", prompt)
+		if streaming {
+			fmt.Printf("Starting %s agent with streaming...\n", backend.Name())
+		} else {
+			fmt.Printf("Starting %s agent...\n", backend.Name())
+		}
+		fmt.Println("Using prompt:\n=====\n", req.Prompt)
 		fmt.Println("=====")
 
-		// Create command with pipes
-		cmd := exec.Command("claude", "--verbose", "-p", prompt, "--output-format", "stream-json")
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
-		
-		// Create pipe for stdout
-		stdout, err := cmd.StdoutPipe()
+		start := time.Now()
+		var result Result
+
+		agent, err := backend.Start(req)
 		if err != nil {
-			done <- fmt.Errorf("failed to create stdout pipe: %v", err)
-			return
-		}
-		
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			done <- fmt.Errorf("failed to start Claude agent: %v", err)
+			result.Err = fmt.Errorf("failed to start %s agent: %v", backend.Name(), err)
+			done <- result
 			return
 		}
+
+		// Stop the agent on cancellation, e.g. Ctrl+C, or if it runs for too long
+		stopped := make(chan error, 1)
+		finished := make(chan struct{})
+		exceeded := make(chan error, 1)
+		watched := make(chan struct{})
+		go func() {
+			defer close(watched)
+			watchAgent(ctx, agent, req, exceeded, stopped, finished)
+		}()
 		
 		// Start stream reader goroutine
 		streamDone := make(chan error, 1)
-		go streamReader(stdout, streamDone)
+		go streamReader(agent.Output(), streaming, &result, req, exceeded, streamDone)
 		
-		// Wait for both command and stream reader to finish
-		cmdErr := cmd.Wait()
+		// Wait for both agent and stream reader to finish
 		streamErr := <-streamDone
+		cmdErr := agent.Wait()
+		close(finished)
+		// The watcher may be sending why it stopped the agent, wait for it to return
+		<-watched
+		result.Duration = time.Since(start)
+		if !result.costReported {
+			// Stopped before the final result event, keep what was counted from the stream
+			result.CostUSD = result.estimatedCost
+			result.Estimated = result.estimatedCost > 0
+			result.NumTurns = result.turns
+		}
 		
 		// Determine final error
-		var finalErr error
+		select {
+		case reason := <-stopped:
+			cmdErr = reason
+		default:
+			if result.maxTurnsReached {
+				cmdErr = &LimitError{MaxTurns: result.NumTurns}
+			}
+		}
 		if cmdErr != nil {
-			finalErr = fmt.Errorf("Claude agent error: %v", cmdErr)
+			result.Err = fmt.Errorf("%s agent error: %w", backend.Name(), cmdErr)
 		} else if streamErr != nil {
-			finalErr = streamErr
+			result.Err = streamErr
 		}
 		
-		if finalErr != nil {
-			fmt.Fprintf(os.Stderr, "Error running Claude agent: %v\n", finalErr)
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error running %s agent: %v\n", backend.Name(), result.Err)
 		} else {
-			fmt.Println("Claude agent finished.")
+			fmt.Printf("%s agent finished.\n", backend.Name())
 		}
 		
-		done <- finalErr
+		done <- result
 	}()
 	
 	return done
@@ -0,0 +1,259 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fynardo/astropath/internal/proc"
+)
+
+// scriptedBackend starts agents that print the given lines, then run until they are stopped
+type scriptedBackend struct {
+	lines         []string
+	ignoreSignals bool           // Agents keep running when signaled, until they are killed
+	agent         *scriptedAgent // Last agent started
+}
+
+func (b *scriptedBackend) Name() string {
+	return "scripted"
+}
+
+func (b *scriptedBackend) Start(req Request) (Agent, error) {
+	agent := &scriptedAgent{lines: make(chan string), stopped: make(chan struct{}), ignoreSignals: b.ignoreSignals}
+	b.agent = agent
+	go func() {
+		defer close(agent.lines)
+		for _, line := range b.lines {
+			select {
+			case agent.lines <- line:
+				if strings.Contains(line, `"type":"assistant"`) {
+					agent.turns.Add(1)
+				}
+			case <-agent.stopped:
+				return
+			}
+		}
+		<-agent.stopped
+	}()
+	return agent, nil
+}
+
+type scriptedAgent struct {
+	lines         chan string
+	stopped       chan struct{}
+	ignoreSignals bool
+	turns         atomic.Int32 // Assistant messages delivered before the agent was stopped
+	signal        atomic.Value // Last signal received
+	killed        atomic.Bool
+}
+
+func (a *scriptedAgent) Output() <-chan string {
+	return a.lines
+}
+
+func (a *scriptedAgent) Wait() error {
+	for range a.lines {
+	}
+	return nil
+}
+
+func (a *scriptedAgent) Signal(sig os.Signal) error {
+	a.signal.Store(sig)
+	if a.ignoreSignals {
+		return nil
+	}
+	a.stop()
+	return nil
+}
+
+func (a *scriptedAgent) Cancel() error {
+	a.killed.Store(true)
+	a.stop()
+	return nil
+}
+
+func (a *scriptedAgent) stop() {
+	select {
+	case <-a.stopped:
+	default:
+		close(a.stopped)
+	}
+}
+
+// assistantLine is an assistant message of the stream with the given usage
+func assistantLine(id int, input int, output int) string {
+	return fmt.Sprintf(`{"type":"assistant","session_id":"s1","message":{"id":"m%d","model":"claude-sonnet-4-5","content":[{"type":"text","text":"turn %d"}],"usage":{"input_tokens":%d,"output_tokens":%d}}}`, id, id, input, output)
+}
+
+func TestRunAgentLimits(t *testing.T) {
+	// Every turn costs 1000 input tokens ($0.003) and 1000 output tokens ($0.015) with Sonnet
+	lines := []string{`{"type":"system","subtype":"init","session_id":"s1","model":"claude-sonnet-4-5"}`}
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, assistantLine(i, 1000, 1000))
+	}
+	backend := &scriptedBackend{lines: lines}
+
+	tests := []struct {
+		name      string
+		req       Request
+		wantLimit LimitError
+	}{
+		{"max turns", Request{MaxTurns: 3}, LimitError{MaxTurns: 3}},
+		{"max cost", Request{MaxCost: 0.05}, LimitError{MaxCost: 0.05}},
+		{"max tokens", Request{MaxTokens: 5000}, LimitError{MaxTokens: 5000}},
+		{"timeout", Request{Timeout: 200 * time.Millisecond}, LimitError{Timeout: 200 * time.Millisecond}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := <-RunAgent(context.Background(), backend, test.req)
+
+			var limitErr *LimitError
+			if !errors.As(result.Err, &limitErr) || *limitErr != test.wantLimit {
+				t.Fatalf("Err = %v, want %v", result.Err, &test.wantLimit)
+			}
+			// No result event came, the turns are those streamed until the agent was stopped
+			if want := int(backend.agent.turns.Load()); result.NumTurns != want || want == 0 {
+				t.Errorf("NumTurns = %d, want the %d streamed turns", result.NumTurns, want)
+			}
+			if !result.Estimated || result.CostUSD <= 0 {
+				t.Errorf("CostUSD = %v (estimated %v), want an estimate", result.CostUSD, result.Estimated)
+			}
+			if result.SessionID != "s1" {
+				t.Errorf("SessionID = %q, want s1", result.SessionID)
+			}
+		})
+	}
+}
+
+func TestRunAgentReportedResult(t *testing.T) {
+	backend := &scriptedBackend{lines: []string{
+		assistantLine(1, 1000, 1000),
+		assistantLine(2, 1000, 1000),
+		`{"type":"result","subtype":"success","session_id":"s1","num_turns":5,"total_cost_usd":0.5}`,
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := RunAgent(ctx, backend, Request{})
+	// The scripted agent runs until it is stopped, like an agent that doesn't exit after its result
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	result := <-done
+
+	if result.NumTurns != 5 || result.CostUSD != 0.5 || result.Estimated {
+		t.Errorf("NumTurns = %d, CostUSD = %v, Estimated = %v, want the values of the result event", result.NumTurns, result.CostUSD, result.Estimated)
+	}
+}
+
+func TestRunAgentCancelled(t *testing.T) {
+	defer func(grace time.Duration) { proc.GracePeriod = grace }(proc.GracePeriod)
+	proc.GracePeriod = 200 * time.Millisecond
+
+	tests := []struct {
+		name          string
+		ignoreSignals bool
+		wantKilled    bool
+	}{
+		{"exits when signaled", false, false},
+		{"killed after the grace period", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := &scriptedBackend{lines: []string{assistantLine(1, 10, 10)}, ignoreSignals: test.ignoreSignals}
+			ctx, cancel := context.WithCancelCause(context.Background())
+			time.AfterFunc(100*time.Millisecond, func() { cancel(&proc.SignalError{Signal: os.Interrupt}) })
+
+			start := time.Now()
+			result := <-RunAgent(ctx, backend, Request{})
+			elapsed := time.Since(start)
+
+			if !proc.Interrupted(result.Err) {
+				t.Errorf("Err = %v, want the interruption", result.Err)
+			}
+			// The signal received by Astropath is forwarded to the agent
+			if sig := backend.agent.signal.Load(); sig != os.Interrupt {
+				t.Errorf("agent signal = %v, want %v", sig, os.Interrupt)
+			}
+			if killed := backend.agent.killed.Load(); killed != test.wantKilled {
+				t.Errorf("agent killed = %v, want %v", killed, test.wantKilled)
+			}
+			if test.wantKilled && elapsed < 100*time.Millisecond+proc.GracePeriod {
+				t.Errorf("agent killed after %s, want it to have the grace period", elapsed)
+			}
+		})
+	}
+}
+
+func TestObserveTurns(t *testing.T) {
+	message := func(id string) *Event {
+		return &Event{Type: AssistantEvent, Message: &Message{ID: id}}
+	}
+	tests := []struct {
+		name   string
+		events []*Event
+		want   int
+	}{
+		{"one message per turn", []*Event{message("a"), message("b"), message("c")}, 3},
+		{"content blocks of a message", []*Event{message("a"), message("a"), message("b")}, 2},
+		{"messages without an ID", []*Event{message(""), message(""), message("")}, 3},
+		{"message without an ID after one with an ID", []*Event{message("a"), message(""), message("a")}, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result Result
+			for _, event := range test.events {
+				result.Observe(event)
+			}
+			if result.turns != test.want {
+				t.Errorf("turns = %d, want %d", result.turns, test.want)
+			}
+		})
+	}
+}
+
+func TestObserveEstimatedCost(t *testing.T) {
+	usage := &Usage{InputTokens: 1000, OutputTokens: 1000}
+	var result Result
+	result.Observe(&Event{Type: SystemEvent, Subtype: "init", Model: "claude-sonnet-4-5"})
+	// The message model is missing, the one of the session is used
+	result.Observe(&Event{Type: AssistantEvent, Message: &Message{ID: "m1", Usage: usage}})
+	result.Observe(&Event{Type: AssistantEvent, Message: &Message{ID: "m2", Model: "claude-haiku-4-5", Usage: usage}})
+
+	// Sonnet costs $0.018 and Haiku $0.006 for 1000 input and 1000 output tokens
+	if got := fmt.Sprintf("%.4f", result.cost()); got != "0.0240" {
+		t.Errorf("cost() = %s, want the estimate 0.0240", got)
+	}
+	if result.Usage.InputTokens != 2000 || result.Usage.OutputTokens != 2000 {
+		t.Errorf("Usage = %+v, want the sum of the messages", result.Usage)
+	}
+
+	result.Observe(&Event{Type: ResultEvent, TotalCostUSD: 0.5})
+	if got := result.cost(); got != 0.5 {
+		t.Errorf("cost() = %v, want the reported 0.5", got)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	usage := Usage{InputTokens: 1000000, OutputTokens: 1000000}
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"claude-sonnet-4-5-20250929", 18},
+		{"claude-opus-4-1-20250805", 90},
+		{"claude-opus-4-5-20251101", 30},
+		{"claude-haiku-4-5", 6},
+		{"claude-3-5-haiku-20241022", 4.8},
+		{"unknown", 90},
+	}
+	for _, test := range tests {
+		if got := EstimateCost(test.model, usage); fmt.Sprintf("%.4f", got) != fmt.Sprintf("%.4f", test.want) {
+			t.Errorf("EstimateCost(%s) = %v, want %v", test.model, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Event types emitted by 'claude --output-format stream-json'
+const (
+	SystemEvent    = "system"
+	AssistantEvent = "assistant"
+	UserEvent      = "user"
+	ResultEvent    = "result"
+)
+
+// Content block types found inside assistant and user messages
+const (
+	TextBlock       = "text"
+	ThinkingBlock   = "thinking"
+	ToolUseBlock    = "tool_use"
+	ToolResultBlock = "tool_result"
+)
+
+// ErrNotJSON is returned by the parser for output lines that are not stream-json events
+var ErrNotJSON = errors.New("line is not a stream-json event")
+
+// maxPendingSize bounds the data buffered while waiting for the rest of a partial line
+const maxPendingSize = 16 * 1024 * 1024
+
+// Event is a single stream-json event. Fields that don't apply to an event type are left empty.
+// Unknown fields are ignored.
+type Event struct {
+	Type      string `json:"type"`
+	Subtype   string `json:"subtype,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+
+	// system/init fields
+	Model string   `json:"model,omitempty"`
+	Cwd   string   `json:"cwd,omitempty"`
+	Tools []string `json:"tools,omitempty"`
+
+	// assistant and user fields
+	Message *Message `json:"message,omitempty"`
+
+	// result fields
+	IsError       bool    `json:"is_error,omitempty"`
+	Result        string  `json:"result,omitempty"`
+	NumTurns      int     `json:"num_turns,omitempty"`
+	DurationMs    int64   `json:"duration_ms,omitempty"`
+	DurationAPIMs int64   `json:"duration_api_ms,omitempty"`
+	TotalCostUSD  float64 `json:"total_cost_usd,omitempty"`
+	Usage         *Usage  `json:"usage,omitempty"`
+
+	// Raw is the original line the event was parsed from
+	Raw string `json:"-"`
+}
+
+// Message is the conversation message carried by assistant and user events.
+type Message struct {
+	ID      string         `json:"id,omitempty"`
+	Role    string         `json:"role,omitempty"`
+	Model   string         `json:"model,omitempty"`
+	Content []ContentBlock `json:"content,omitempty"`
+	Usage   *Usage         `json:"usage,omitempty"`
+}
+
+// ContentBlock is a piece of a message: text, a tool call or a tool result.
+type ContentBlock struct {
+	Type string `json:"type"`
+
+	// text and thinking blocks
+	Text     string `json:"text,omitempty"`
+	Thinking string `json:"thinking,omitempty"`
+
+	// tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result blocks
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// Usage holds the token counts reported by the API.
+type Usage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// UnmarshalJSON accepts message content both as a plain string and as a list of blocks.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	var raw struct {
+		message
+		Content json.RawMessage `json:"content,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = Message(raw.message)
+	m.Content = nil
+	if len(raw.Content) == 0 {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw.Content, &text); err == nil {
+		m.Content = []ContentBlock{{Type: TextBlock, Text: text}}
+		return nil
+	}
+	return json.Unmarshal(raw.Content, &m.Content)
+}
+
+// ResultText returns the text of a tool_result block, whose content is either a string or a list of blocks.
+func (b ContentBlock) ResultText() string {
+	if len(b.Content) == 0 {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(b.Content, &text); err == nil {
+		return text
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(b.Content, &blocks); err != nil {
+		return string(b.Content)
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// IsInit reports whether the event is the system/init event that opens a session.
+func (e Event) IsInit() bool {
+	return e.Type == SystemEvent && e.Subtype == "init"
+}
+
+// Total returns the sum of all token counts.
+func (u Usage) Total() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Spent returns the tokens counted against the token limits: input, output and cache writes.
+// Cache reads, which repeat the context at every turn, are left out.
+func (u Usage) Spent() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens
+}
+
+// Add accumulates the token counts of other into u.
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+}
+
+// Parser turns agent output lines into events.
+// A JSON object split over several lines is buffered until it is complete.
+type Parser struct {
+	pending   string
+	discarded string
+}
+
+// Parse parses an output line. It returns nil without error when the line is an incomplete event
+// that needs more input, and ErrNotJSON when the line is plain text. When the line doesn't complete
+// the buffered partial event, the partial event is given up, returned by Discarded, and the line is parsed on its own.
+func (p *Parser) Parse(line string) (*Event, error) {
+	p.discarded = ""
+	data := line
+	if p.pending != "" {
+		data = p.pending + line
+	} else if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return nil, ErrNotJSON
+	}
+
+	var event Event
+	err := json.Unmarshal([]byte(data), &event)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(data)) && len(data) < maxPendingSize {
+			// Unexpected end of input, wait for the rest of the event
+			p.pending = data
+			return nil, nil
+		}
+		if pending := p.pending; pending != "" {
+			p.pending = ""
+			event, err := p.Parse(line)
+			p.discarded = pending
+			return event, err
+		}
+		return nil, err
+	}
+
+	p.pending = ""
+	event.Raw = data
+	return &event, nil
+}
+
+// Discarded returns the partial event given up by the last call to Parse, empty if none
+func (p *Parser) Discarded() string {
+	return p.discarded
+}
+
+// Flush returns any buffered partial event as plain text and resets the parser.
+func (p *Parser) Flush() string {
+	pending := p.pending
+	p.pending = ""
+	return pending
+}
+
+// ParseEvent parses a single complete stream-json line.
+func ParseEvent(line string) (*Event, error) {
+	var p Parser
+	event, err := p.Parse(line)
+	if err == nil && event == nil {
+		return nil, errors.New("incomplete stream-json event")
+	}
+	return event, err
+}
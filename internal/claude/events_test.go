@@ -0,0 +1,107 @@
+package claude
+
+import (
+	"errors"
+	"testing"
+)
+
+// parsed is what Parse returned for a line: the event type, "text" for ErrNotJSON,
+// "error" for other errors and "" for an incomplete event
+type parsed struct {
+	Kind      string
+	Discarded string
+}
+
+func TestParser(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		want      []parsed
+		wantFlush string
+	}{
+		{
+			name:  "complete events",
+			lines: []string{`{"type":"system","subtype":"init"}`, ` {"type":"result"}`},
+			want:  []parsed{{Kind: SystemEvent}, {Kind: ResultEvent}},
+		},
+		{
+			name:  "plain text",
+			lines: []string{"hello", "", `{"type":"assistant"}`},
+			want:  []parsed{{Kind: "text"}, {Kind: "text"}, {Kind: AssistantEvent}},
+		},
+		{
+			name:  "event split over lines",
+			lines: []string{`{"type":"assi`, `stant","session_id":`, `"s1"}`},
+			want:  []parsed{{}, {}, {Kind: AssistantEvent}},
+		},
+		{
+			name:  "invalid JSON",
+			lines: []string{`{"type":x}`, `{"type":"user"}`},
+			want:  []parsed{{Kind: "error"}, {Kind: UserEvent}},
+		},
+		{
+			name:  "text after a partial event",
+			lines: []string{`{"type":"assistant",`, "Killed"},
+			want:  []parsed{{}, {Kind: "text", Discarded: `{"type":"assistant",`}},
+		},
+		{
+			name:  "event after a partial event",
+			lines: []string{`{"type":"assi`, `{"type":"result"}`},
+			want:  []parsed{{}, {Kind: ResultEvent, Discarded: `{"type":"assi`}},
+		},
+		{
+			name:  "partial event after a partial event",
+			lines: []string{`{"type":"assi`, `{"type":"res`, `ult"}`},
+			want:  []parsed{{}, {Discarded: `{"type":"assi`}, {Kind: ResultEvent}},
+		},
+		{
+			name:      "partial event at the end",
+			lines:     []string{`{"type":"result"}`, `{"type":"assi`},
+			want:      []parsed{{Kind: ResultEvent}, {}},
+			wantFlush: `{"type":"assi`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var parser Parser
+			for i, line := range test.lines {
+				event, err := parser.Parse(line)
+				got := parsed{Discarded: parser.Discarded()}
+				switch {
+				case errors.Is(err, ErrNotJSON):
+					got.Kind = "text"
+				case err != nil:
+					got.Kind = "error"
+				case event != nil:
+					got.Kind = event.Type
+				}
+				if got != test.want[i] {
+					t.Errorf("Parse(%q) = %+v, want %+v", line, got, test.want[i])
+				}
+			}
+			if flush := parser.Flush(); flush != test.wantFlush {
+				t.Errorf("Flush() = %q, want %q", flush, test.wantFlush)
+			}
+			if flush := parser.Flush(); flush != "" {
+				t.Errorf("second Flush() = %q, want nothing", flush)
+			}
+		})
+	}
+}
+
+func TestParseEventRaw(t *testing.T) {
+	var parser Parser
+	parser.Parse(`{"type":"result",`)
+	event, err := parser.Parse(`"num_turns":3}`)
+	if err != nil || event == nil {
+		t.Fatalf("Parse() = %v, %v", event, err)
+	}
+	if event.NumTurns != 3 || event.Raw != `{"type":"result","num_turns":3}` {
+		t.Errorf("event = %+v, want the joined event", event)
+	}
+
+	if _, err := ParseEvent(`{"type":"result",`); err == nil {
+		t.Error("ParseEvent() of a partial event succeeded")
+	}
+}
@@ -0,0 +1,96 @@
+package claude
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+func init() {
+	RegisterBackend("fake", func() Backend { return &FakeBackend{} })
+}
+
+// FakeBackend emulates an agent without launching any process.
+// It replies with a fixed stream-json conversation, which is useful to try
+// Astropath commands and pipelines without spending tokens.
+type FakeBackend struct{}
+
+func (b *FakeBackend) Name() string {
+	return "fake"
+}
+
+func (b *FakeBackend) Start(req Request) (Agent, error) {
+	agent := &fakeAgent{
+		lines:    make(chan string),
+		canceled: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(agent.lines)
+		for _, line := range fakeTranscript(req) {
+			select {
+			case agent.lines <- line:
+			case <-agent.canceled:
+				return
+			}
+		}
+	}()
+
+	return agent, nil
+}
+
+type fakeAgent struct {
+	lines    chan string
+	canceled chan struct{}
+}
+
+func (a *fakeAgent) Output() <-chan string {
+	return a.lines
+}
+
+func (a *fakeAgent) Wait() error {
+	for range a.lines {
+	}
+	return nil
+}
+
+func (a *fakeAgent) Signal(sig os.Signal) error {
+	return a.Cancel()
+}
+
+func (a *fakeAgent) Cancel() error {
+	select {
+	case <-a.canceled:
+	default:
+		close(a.canceled)
+	}
+	return nil
+}
+
+// fakeTranscript builds the stream-json lines the fake agent replies with
+func fakeTranscript(req Request) []string {
+	prompt := req.Prompt
+	firstLine := strings.TrimSpace(strings.SplitN(prompt, "\n", 2)[0])
+	model := req.Model
+	if model == "" {
+		model = "fake"
+	}
+
+	events := []map[string]interface{}{
+		{"type": "system", "subtype": "init", "session_id": "fake-session", "model": model},
+		{"type": "assistant", "session_id": "fake-session", "message": map[string]interface{}{
+			"role":    "assistant",
+			"content": []map[string]interface{}{{"type": "text", "text": "Fake agent received: " + firstLine}},
+		}},
+		{"type": "result", "subtype": "success", "session_id": "fake-session", "is_error": false,
+			"result": "Fake agent done.", "num_turns": 1, "duration_ms": 0, "total_cost_usd": 0,
+			"usage": map[string]interface{}{"input_tokens": len(prompt) / 4, "output_tokens": len(firstLine) / 4}},
+	}
+
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		data, _ := json.Marshal(event)
+		lines = append(lines, string(data))
+	}
+	return lines
+}
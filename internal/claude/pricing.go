@@ -0,0 +1,42 @@
+package claude
+
+import "strings"
+
+// modelPrice is the price of a model in USD per million tokens
+type modelPrice struct {
+	Match  string // Part of the model id, e.g. "sonnet"
+	Input  float64
+	Output float64
+}
+
+// modelPrices are matched in order against the model id, more specific entries first.
+// Cache writes cost 1.25 times the input price, cache reads 0.1 times.
+var modelPrices = []modelPrice{
+	{Match: "opus-4-1", Input: 15, Output: 75},
+	{Match: "opus-4-2025", Input: 15, Output: 75},
+	{Match: "3-opus", Input: 15, Output: 75},
+	{Match: "opus", Input: 5, Output: 25},
+	{Match: "sonnet", Input: 3, Output: 15},
+	{Match: "3-5-haiku", Input: 0.8, Output: 4},
+	{Match: "3-haiku", Input: 0.25, Output: 1.25},
+	{Match: "haiku", Input: 1, Output: 5},
+}
+
+// unknownModelPrice is used for models missing from the table, priced like the most expensive ones
+// so the cost limits err on the safe side
+var unknownModelPrice = modelPrice{Input: 15, Output: 75}
+
+// EstimateCost returns the approximate cost in USD of the tokens used with a model.
+// It only serves to enforce the cost limits while the agent runs, the agent reports the actual cost when it finishes.
+func EstimateCost(model string, usage Usage) float64 {
+	price := unknownModelPrice
+	for _, candidate := range modelPrices {
+		if strings.Contains(model, candidate.Match) {
+			price = candidate
+			break
+		}
+	}
+
+	input := float64(usage.InputTokens) + 1.25*float64(usage.CacheCreationInputTokens) + 0.1*float64(usage.CacheReadInputTokens)
+	return (input*price.Input + float64(usage.OutputTokens)*price.Output) / 1e6
+}
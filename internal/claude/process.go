@@ -0,0 +1,96 @@
+package claude
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/fynardo/astropath/internal/proc"
+)
+
+// maxLineSize bounds a single line of agent output, stream-json events can be large
+var maxLineSize = 16 * 1024 * 1024
+
+// ProcessAgent is an Agent backed by a local process whose stdout is read line by line.
+// Backends wrapping other agent CLIs can reuse it through StartProcess.
+type ProcessAgent struct {
+	cmd     *exec.Cmd
+	lines   chan string
+	readErr chan error
+}
+
+// StartProcess launches the given command and streams its stdout as an Agent.
+// Stderr is inherited from Astropath. The process runs in its own process group, so signals and Cancel
+// reach the processes it spawns too. Its stdin is /dev/null: agents get their prompt as an argument, and
+// a background process group reading from the terminal would be stopped by SIGTTIN, ignoring SIGTERM.
+func StartProcess(name string, args ...string) (*ProcessAgent, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = nil
+	proc.SetGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	agent := &ProcessAgent{
+		cmd:     cmd,
+		lines:   make(chan string),
+		readErr: make(chan error, 1),
+	}
+
+	go func() {
+		defer close(agent.lines)
+
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		for scanner.Scan() {
+			agent.lines <- scanner.Text()
+		}
+		err := scanner.Err()
+		if err != nil {
+			// Keep reading until the agent exits, or it would block writing to a full pipe and never finish
+			fmt.Fprintf(os.Stderr, "Warning: could not read the agent output, discarding the rest of it: %v\n", err)
+			io.Copy(io.Discard, stdout)
+		}
+		agent.readErr <- err
+	}()
+
+	return agent, nil
+}
+
+func (a *ProcessAgent) Output() <-chan string {
+	return a.lines
+}
+
+// Wait drains any unread output and waits for the process to exit.
+func (a *ProcessAgent) Wait() error {
+	for range a.lines {
+	}
+	readErr := <-a.readErr
+
+	if err := a.cmd.Wait(); err != nil {
+		return err
+	}
+	if readErr != nil {
+		return fmt.Errorf("error reading stream: %v", readErr)
+	}
+	return nil
+}
+
+// Signal sends a signal to the process group of the agent
+func (a *ProcessAgent) Signal(sig os.Signal) error {
+	return proc.SignalGroup(a.cmd, sig)
+}
+
+// Cancel kills the process group of the agent
+func (a *ProcessAgent) Cancel() error {
+	return proc.KillGroup(a.cmd)
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package claude
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartProcessStdin(t *testing.T) {
+	// An agent reading its stdin gets EOF right away instead of blocking on the terminal
+	agent, err := StartProcess("sh", "-c", "read line; echo read:$?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent.cmd.Stdin != nil {
+		t.Error("the agent inherits the stdin of Astropath")
+	}
+
+	var lines []string
+	done := make(chan error, 1)
+	go func() {
+		for line := range agent.Output() {
+			lines = append(lines, line)
+		}
+		done <- agent.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Wait() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		agent.Cancel()
+		t.Fatal("the agent is still waiting for input")
+	}
+	if len(lines) != 1 || lines[0] != "read:1" {
+		t.Errorf("output = %q, want [\"read:1\"]", lines)
+	}
+}
+
+func TestStartProcessLineTooLong(t *testing.T) {
+	defer func(size int) { maxLineSize = size }(maxLineSize)
+	maxLineSize = 64 * 1024
+
+	// A line over the limit followed by more output than a pipe holds
+	agent, err := StartProcess("sh", "-c", "head -c 131072 /dev/zero | tr '\\0' x; echo; head -c 1048576 /dev/zero | tr '\\0' '\\n'; echo done")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		for range agent.Output() {
+		}
+		done <- agent.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "error reading stream") {
+			t.Errorf("Wait() = %v, want the read error", err)
+		}
+	case <-time.After(5 * time.Second):
+		agent.Cancel()
+		t.Fatal("the agent is blocked writing its output")
+	}
+}
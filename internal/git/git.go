@@ -0,0 +1,336 @@
+// Package git wraps the git commands Astropath runs on the current repository.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// run executes a git command and returns its trimmed stdout
+func run(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// CurrentBranch returns the name of the checked out branch, or an empty string on a detached HEAD.
+func CurrentBranch() (string, error) {
+	if _, err := run("rev-parse", "--git-dir"); err != nil {
+		return "", err
+	}
+	branch, err := run("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		// HEAD is not a symbolic ref: detached
+		return "", nil
+	}
+	return branch, nil
+}
+
+// BranchExists reports whether a local branch with the given name exists.
+func BranchExists(name string) bool {
+	_, err := run("rev-parse", "--verify", "--quiet", "refs/heads/"+name)
+	return err == nil
+}
+
+// RemoteBranchExists reports whether the remote-tracking branch of origin with the given name exists, e.g. origin/main.
+func RemoteBranchExists(name string) bool {
+	_, err := run("rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+name)
+	return err == nil
+}
+
+// Checkout switches to an existing branch.
+func Checkout(name string) error {
+	_, err := run("checkout", name)
+	return err
+}
+
+// CreateBranch creates a new branch from the given start point and switches to it.
+// An empty start point creates it from the current HEAD. The new branch doesn't track the start point,
+// even when it is a remote-tracking branch.
+func CreateBranch(name string, startPoint string) error {
+	args := []string{"checkout", "--no-track", "-b", name}
+	if startPoint != "" {
+		args = append(args, startPoint)
+	}
+	_, err := run(args...)
+	return err
+}
+
+// DefaultBranch detects the base branch of the repository: the branch origin/HEAD points to,
+// or the first existing branch among the usual trunk names. It returns an empty string if none is found.
+func DefaultBranch() string {
+	if ref, err := run("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	for _, name := range []string{"main", "master", "trunk", "develop"} {
+		if BranchExists(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// IsRange reports whether the revision is a commit range such as 'a..b' or 'a...b'.
+func IsRange(rev string) bool {
+	return strings.Contains(rev, "..")
+}
+
+// ValidRevision reports whether the revision, or both ends of a commit range, resolve to commits.
+func ValidRevision(rev string) bool {
+	_, err := run("rev-parse", "--end-of-options", rev, "--")
+	return err == nil
+}
+
+// ValidBranchName reports whether the name is a valid git branch name.
+func ValidBranchName(name string) bool {
+	_, err := run("check-ref-format", "--branch", name)
+	return err == nil
+}
+
+// RepoRoot returns the top level directory of the working copy.
+func RepoRoot() (string, error) {
+	return run("rev-parse", "--show-toplevel")
+}
+
+// RangeEnds splits a commit range such as 'a..b' or 'a...b' into its ends. A missing end stands for HEAD.
+func RangeEnds(rev string) (string, string) {
+	sep := ".."
+	if strings.Contains(rev, "...") {
+		sep = "..."
+	}
+	from, to, _ := strings.Cut(rev, sep)
+	if from == "" {
+		from = "HEAD"
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	return from, to
+}
+
+// CommitID returns the full hash of the commit a revision points to.
+func CommitID(rev string) (string, error) {
+	return run("rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+}
+
+// RemoteURL returns the URL of a remote, or an empty string if it isn't configured.
+func RemoteURL(name string) string {
+	url, err := run("remote", "get-url", name)
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// runInput executes a git command with the given stdin and extra environment, and returns its trimmed stdout
+func runInput(input string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// HasTrailer reports whether a commit message ends with the trailer "key: value".
+func HasTrailer(message string, key string, value string) (bool, error) {
+	trailers, err := runInput(message, nil, "interpret-trailers", "--parse")
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(trailers, "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) && strings.TrimSpace(v) == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// runCommits returns the commits made on the checked out branch since from, oldest first, each followed by its parents.
+// Only the first-parent history counts: commits brought in by merges, and commits reachable from base
+// when it is given, don't belong to the branch.
+func runCommits(from string, base string) ([][]string, error) {
+	args := []string{"rev-list", "--reverse", "--first-parent", "--parents", from + "..HEAD"}
+	if base != "" {
+		args = append(args, "--not", base)
+	}
+	list, err := run(args...)
+	if err != nil || list == "" {
+		return nil, err
+	}
+	var commits [][]string
+	for _, line := range strings.Split(list, "\n") {
+		commits = append(commits, strings.Fields(line))
+	}
+	return commits, nil
+}
+
+// missingTrailer reports whether a commit isn't a merge and its message lacks the trailer "key: value"
+func missingTrailer(ids []string, key string, value string) (bool, error) {
+	if len(ids) > 2 {
+		return false, nil
+	}
+	message, err := run("log", "-1", "--format=%B", ids[0])
+	if err != nil {
+		return false, err
+	}
+	has, err := HasTrailer(message, key, value)
+	return !has, err
+}
+
+// MissingTrailer returns the short ids of the commits made on the checked out branch since from, see runCommits,
+// that lack the trailer "key: value". Merge commits are left out.
+func MissingTrailer(from string, base string, key string, value string) ([]string, error) {
+	commits, err := runCommits(from, base)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, ids := range commits {
+		lacks, err := missingTrailer(ids, key, value)
+		if err != nil {
+			return nil, err
+		}
+		if lacks {
+			short, err := run("rev-parse", "--short", ids[0])
+			if err != nil {
+				return nil, err
+			}
+			missing = append(missing, short)
+		}
+	}
+	return missing, nil
+}
+
+// AddTrailer adds the trailer "key: value" to the commits made on the checked out branch since from,
+// see runCommits, that don't have it. Merge commits aren't tagged, only given their rewritten first parent.
+// The commits are rewritten with the same trees, authors and dates, so the working copy doesn't change.
+// Signed commits are never rewritten, as that would drop their signature. It returns the number of tagged commits.
+func AddTrailer(from string, base string, key string, value string) (int, error) {
+	branch, err := CurrentBranch()
+	if err != nil {
+		return 0, err
+	}
+	if branch == "" {
+		return 0, fmt.Errorf("can't rewrite commits on a detached HEAD")
+	}
+	head, err := CommitID("HEAD")
+	if err != nil {
+		return 0, err
+	}
+	commits, err := runCommits(from, base)
+	if err != nil || len(commits) == 0 {
+		return 0, err
+	}
+
+	// Check everything before writing any commit
+	rewrite := false
+	untagged := map[string]bool{}
+	for _, ids := range commits {
+		lacks, err := missingTrailer(ids, key, value)
+		if err != nil {
+			return 0, err
+		}
+		untagged[ids[0]] = lacks
+		rewrite = rewrite || lacks
+		if rewrite && isSigned(ids[0]) {
+			return 0, fmt.Errorf("commit %s is signed, adding the trailer would drop its signature", ids[0])
+		}
+	}
+	if !rewrite {
+		return 0, nil
+	}
+
+	tagged := 0
+	parent := ""
+	for _, ids := range commits {
+		commit, parents := ids[0], ids[1:]
+		if parent != "" {
+			parents[0] = parent
+		}
+		if parent == "" && !untagged[commit] {
+			continue
+		}
+
+		message, err := run("log", "-1", "--format=%B", commit)
+		if err != nil {
+			return 0, err
+		}
+		if untagged[commit] {
+			tagged++
+			message, err = runInput(message+"\n", nil, "interpret-trailers", "--trailer", key+": "+value)
+			if err != nil {
+				return 0, err
+			}
+		}
+		parent, err = recommit(commit, parents, message)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := run("update-ref", "-m", "astropath: add "+key+" trailer", "refs/heads/"+branch, parent, head); err != nil {
+		return 0, err
+	}
+	return tagged, nil
+}
+
+// isSigned reports whether a commit carries a GPG or SSH signature
+func isSigned(commit string) bool {
+	object, err := run("cat-file", "commit", commit)
+	if err != nil {
+		return false
+	}
+	header, _, _ := strings.Cut(object, "\n\n")
+	for _, line := range strings.Split(header, "\n") {
+		if strings.HasPrefix(line, "gpgsig") {
+			return true
+		}
+	}
+	return false
+}
+
+// recommit creates a copy of a commit with other parents and message, keeping its tree, author and committer
+func recommit(commit string, parents []string, message string) (string, error) {
+	info, err := run("log", "-1", "--format=%T%x00%an%x00%ae%x00%aI%x00%cn%x00%ce%x00%cI", commit)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Split(info, "\x00")
+	if len(fields) != 7 {
+		return "", fmt.Errorf("unexpected details of commit %s: %q", commit, info)
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=" + fields[1], "GIT_AUTHOR_EMAIL=" + fields[2], "GIT_AUTHOR_DATE=" + fields[3],
+		"GIT_COMMITTER_NAME=" + fields[4], "GIT_COMMITTER_EMAIL=" + fields[5], "GIT_COMMITTER_DATE=" + fields[6],
+	}
+
+	args := []string{"commit-tree", fields[0]}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+	return runInput(message+"\n", env, args...)
+}
@@ -0,0 +1,156 @@
+package git
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/fynardo/astropath/internal/git/gittest"
+)
+
+func TestHasTrailer(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"Fix the parser", false},
+		{"Fix the parser\n\nAstropath-Task: parser", true},
+		{"Fix the parser\n\nastropath-task: parser", true},
+		{"Fix the parser\n\nAstropath-Task: other", false},
+		{"Fix the parser\n\nAstropath-Task: parser\nSigned-off-by: Test <test@example.com>", true},
+		{"Astropath-Task: parser in the middle\n\nof the message", false},
+	}
+	for _, test := range tests {
+		got, err := HasTrailer(test.message, "Astropath-Task", "parser")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("HasTrailer(%q) = %v, want %v", test.message, got, test.want)
+		}
+	}
+}
+
+func TestAddTrailer(t *testing.T) {
+	gittest.NewRepo(t)
+	gittest.Git(t, "checkout", "-q", "-b", "feature")
+	start := gittest.Git(t, "rev-parse", "HEAD")
+	gittest.Commit(t, "First change")
+	gittest.Commit(t, "Second change\n\nAstropath-Task: parser")
+	gittest.Commit(t, "Third change")
+	tree := gittest.Git(t, "rev-parse", "HEAD^{tree}")
+
+	missing, err := MissingTrailer(start, "main", "Astropath-Task", "parser")
+	if err != nil || len(missing) != 2 {
+		t.Errorf("MissingTrailer() = %v, %v, want 2 commits", missing, err)
+	}
+	tagged, err := AddTrailer(start, "main", "Astropath-Task", "parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tagged != 2 {
+		t.Errorf("AddTrailer() tagged %d commits, want 2", tagged)
+	}
+	log := gittest.Git(t, "log", "--format=%B%x00", start+"..feature")
+	for _, message := range strings.Split(log, "\x00") {
+		if message = strings.TrimSpace(message); message != "" && strings.Count(message, "Astropath-Task: parser") != 1 {
+			t.Errorf("commit message %q doesn't have the trailer once", message)
+		}
+	}
+	if got := gittest.Git(t, "rev-parse", "HEAD^{tree}"); got != tree {
+		t.Errorf("the tree changed from %s to %s", tree, got)
+	}
+	if got := gittest.Git(t, "log", "-1", "--format=%an", "HEAD"); got != "Test" {
+		t.Errorf("author = %s, want Test", got)
+	}
+	if status := gittest.Git(t, "status", "--porcelain"); status != "" {
+		t.Errorf("the working copy changed:\n%s", status)
+	}
+
+	// Nothing left to tag
+	head := gittest.Git(t, "rev-parse", "HEAD")
+	if missing, err := MissingTrailer(start, "main", "Astropath-Task", "parser"); err != nil || len(missing) != 0 {
+		t.Errorf("MissingTrailer() after tagging = %v, %v, want none", missing, err)
+	}
+	if tagged, err := AddTrailer(start, "main", "Astropath-Task", "parser"); err != nil || tagged != 0 {
+		t.Errorf("AddTrailer() again = %d, %v, want 0", tagged, err)
+	}
+	if got := gittest.Git(t, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD moved from %s to %s without anything to tag", head, got)
+	}
+}
+
+func TestAddTrailerAfterMerge(t *testing.T) {
+	gittest.NewRepo(t)
+	gittest.Git(t, "checkout", "-q", "-b", "feature")
+	start := gittest.Git(t, "rev-parse", "HEAD")
+	gittest.Commit(t, "Feature change")
+
+	// The agent merges the base branch, which moved on meanwhile
+	gittest.Git(t, "checkout", "-q", "main")
+	gittest.Git(t, "commit", "-q", "--allow-empty", "-m", "Base change")
+	base := gittest.Git(t, "rev-parse", "main")
+	gittest.Git(t, "checkout", "-q", "feature")
+	gittest.Git(t, "merge", "-q", "--no-edit", "main")
+	gittest.Commit(t, "Change after the merge")
+
+	missing, err := MissingTrailer(start, "main", "Astropath-Task", "parser")
+	if err != nil || len(missing) != 2 {
+		t.Errorf("MissingTrailer() = %v, %v, want the 2 feature commits", missing, err)
+	}
+	tagged, err := AddTrailer(start, "main", "Astropath-Task", "parser")
+	if err != nil || tagged != 2 {
+		t.Fatalf("AddTrailer() = %d, %v, want 2 commits", tagged, err)
+	}
+
+	if got := gittest.Git(t, "rev-parse", "main"); got != base {
+		t.Errorf("main moved from %s to %s", base, got)
+	}
+	if out := gittest.Git(t, "merge-base", "--is-ancestor", base, "feature"); out != "" {
+		t.Errorf("merge-base: %s", out)
+	}
+	if message := gittest.Git(t, "log", "-1", "--format=%B", base); strings.Contains(message, "Astropath-Task") {
+		t.Errorf("the base commit was tagged: %q", message)
+	}
+	merge := gittest.Git(t, "log", "-1", "--format=%B", "feature^")
+	if strings.Contains(merge, "Astropath-Task") {
+		t.Errorf("the merge commit was tagged: %q", merge)
+	}
+	if parents := strings.Fields(gittest.Git(t, "log", "-1", "--format=%P", "feature^")); len(parents) != 2 || parents[1] != base {
+		t.Errorf("merge parents = %v, want the base commit second", parents)
+	}
+}
+
+func TestAddTrailerSignedCommit(t *testing.T) {
+	gittest.NewRepo(t)
+	gittest.Git(t, "checkout", "-q", "-b", "feature")
+	start := gittest.Git(t, "rev-parse", "HEAD")
+	gittest.Commit(t, "Unsigned change")
+
+	// A commit with a signature header, git doesn't check it while rewriting
+	object := gittest.Git(t, "cat-file", "commit", "HEAD")
+	header, _, _ := strings.Cut(object, "\n\n")
+	signature := "gpgsig -----BEGIN SSH SIGNATURE-----\n U1NIU0lH\n -----END SSH SIGNATURE-----"
+	signed := writeObject(t, header+"\n"+signature+"\n\nSigned change\n")
+	gittest.Git(t, "update-ref", "refs/heads/feature", signed)
+
+	head := gittest.Git(t, "rev-parse", "HEAD")
+	if _, err := AddTrailer(start, "main", "Astropath-Task", "parser"); err == nil {
+		t.Error("AddTrailer() rewrote a signed commit")
+	}
+	if got := gittest.Git(t, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD moved from %s to %s", head, got)
+	}
+}
+
+// writeObject writes a raw commit object and returns its id
+func writeObject(t *testing.T, content string) string {
+	t.Helper()
+	cmd := exec.Command("git", "hash-object", "-t", "commit", "-w", "--stdin")
+	cmd.Stdin = strings.NewReader(content)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git hash-object: %v", err)
+	}
+	return strings.TrimSpace(string(out))
+}
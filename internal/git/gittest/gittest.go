@@ -0,0 +1,52 @@
+// Package gittest creates throwaway git repositories for the tests of the packages that run git.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// NewRepo creates a git repository with an initial commit on main and makes it the working directory
+// for the rest of the test.
+func NewRepo(t testing.TB) {
+	t.Helper()
+	Chdir(t, t.TempDir())
+	Git(t, "init", "-q", "-b", "main")
+	Commit(t, "initial")
+}
+
+// Git runs a git command in the working directory, as a test user, and returns its trimmed output.
+func Git(t testing.TB, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Commit commits a change to file.txt with the given message.
+func Commit(t testing.TB, message string) {
+	t.Helper()
+	if err := os.WriteFile("file.txt", []byte(message), 0644); err != nil {
+		t.Fatal(err)
+	}
+	Git(t, "add", "file.txt")
+	Git(t, "commit", "-q", "-m", message)
+}
+
+// Chdir makes dir the working directory for the rest of the test.
+func Chdir(t testing.TB, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
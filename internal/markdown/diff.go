@@ -0,0 +1,58 @@
+package markdown
+
+import "strings"
+
+// ChangedSections compares two versions of a document and returns the titles of the top level
+// sections that were added, removed or modified. A modified preamble is reported as an empty title.
+func ChangedSections(before, after *Document) []string {
+	var changed []string
+	if before.Preamble != after.Preamble {
+		changed = append(changed, "")
+	}
+
+	seen := map[string]bool{}
+	for _, section := range after.Sections {
+		key := strings.ToLower(section.Title)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		old := before.TopSection(section.Title)
+		if old == nil || old.String() != section.String() {
+			changed = append(changed, section.Title)
+		}
+	}
+	for _, section := range before.Sections {
+		if !seen[strings.ToLower(section.Title)] {
+			seen[strings.ToLower(section.Title)] = true
+			changed = append(changed, section.Title)
+		}
+	}
+	return changed
+}
+
+// ReplaceSection puts the given section in place of the top level section with the same title,
+// keeping its exact text. The section is added at the end of the document if there is none.
+func (d *Document) ReplaceSection(section *Section) {
+	for i, existing := range d.Sections {
+		if strings.EqualFold(existing.Title, section.Title) {
+			d.Sections[i] = section
+			return
+		}
+	}
+	if d.String() != "" {
+		d.lastBody(ensureBlankLine)
+	}
+	d.Sections = append(d.Sections, section)
+}
+
+// TopSection returns the first top level section with the given title, or nil if there is none.
+// Titles are compared ignoring case. Subsections never match, even with the same title.
+func (d *Document) TopSection(title string) *Section {
+	for _, section := range d.Sections {
+		if strings.EqualFold(section.Title, strings.TrimSpace(title)) {
+			return section
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedSections(t *testing.T) {
+	const before = "Intro\n# One\none\n## Sub\nsub\n# Two\ntwo\n"
+
+	tests := []struct {
+		name  string
+		after string
+		want  []string
+	}{
+		{"unchanged", before, nil},
+		{"preamble", "Other\n# One\none\n## Sub\nsub\n# Two\ntwo\n", []string{""}},
+		{"body", "Intro\n# One\none\n## Sub\nsub\n# Two\nchanged\n", []string{"Two"}},
+		{"subsection", "Intro\n# One\none\n## Sub\nchanged\n# Two\ntwo\n", []string{"One"}},
+		{"heading", "Intro\n# One\none\n## Sub\nsub\n# Two #\ntwo\n", []string{"Two"}},
+		{"added", before + "# Three\n", []string{"Three"}},
+		{"removed", "Intro\n# One\none\n## Sub\nsub\n", []string{"Two"}},
+		{"renamed", "Intro\n# One\none\n## Sub\nsub\n# Deux\ntwo\n", []string{"Deux", "Two"}},
+		{"reordered", "Intro\n# Two\ntwo\n# One\none\n## Sub\nsub\n", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ChangedSections(Parse(before), Parse(test.after))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ChangedSections() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
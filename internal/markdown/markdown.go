@@ -0,0 +1,221 @@
+// Package markdown parses ASTROPATH.md-like files into sections delimited by ATX headings ('# Title').
+//
+// Parsing keeps the original text of every line, so rendering a document back gives the exact
+// same content, and only the sections that were modified change. Lines inside fenced code blocks
+// are never taken as headings.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+
+// Document is a parsed markdown file.
+type Document struct {
+	Preamble string     // Text before the first heading
+	Sections []*Section // Top level sections, in order
+}
+
+// Section is a heading with its content. Deeper headings become subsections.
+type Section struct {
+	Level       int    // Heading level, 1 for '#'
+	Title       string // Heading text
+	Heading     string // Original heading line, including its line break
+	Body        string // Text between the heading and the first subsection
+	Subsections []*Section
+}
+
+// Parse splits the content into sections.
+func Parse(content string) *Document {
+	doc := &Document{}
+	var stack []*Section
+	var fence string
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line == "" {
+			continue
+		}
+
+		if level, title, ok := parseHeading(line); ok && fence == "" {
+			section := &Section{Level: level, Title: title, Heading: line}
+			for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				doc.Sections = append(doc.Sections, section)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Subsections = append(parent.Subsections, section)
+			}
+			stack = append(stack, section)
+			continue
+		}
+
+		fence = updateFence(fence, line)
+		if len(stack) == 0 {
+			doc.Preamble += line
+		} else {
+			stack[len(stack)-1].Body += line
+		}
+	}
+
+	return doc
+}
+
+// parseHeading recognizes ATX heading lines
+func parseHeading(line string) (int, string, bool) {
+	match := headingRe.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if match == nil {
+		return 0, "", false
+	}
+	return len(match[1]), strings.TrimSpace(match[2]), true
+}
+
+// updateFence returns the fence marker that is open after the line, or an empty string outside code blocks
+func updateFence(fence string, line string) string {
+	trimmed := strings.TrimRight(strings.TrimLeft(line, " "), "\r\n")
+	if len(line)-len(strings.TrimLeft(line, " ")) > 3 {
+		return fence
+	}
+
+	for _, char := range []string{"`", "~"} {
+		if !strings.HasPrefix(trimmed, char+char+char) {
+			continue
+		}
+		marker := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, char))]
+		if fence == "" {
+			return marker
+		}
+		// A fence is closed by the same character, at least as long, with nothing after it
+		if strings.HasPrefix(fence, char) && len(marker) >= len(fence) && strings.TrimSpace(trimmed[len(marker):]) == "" {
+			return ""
+		}
+	}
+	return fence
+}
+
+// String renders the document back to markdown.
+func (d *Document) String() string {
+	var b strings.Builder
+	b.WriteString(d.Preamble)
+	for _, section := range d.Sections {
+		section.render(&b)
+	}
+	return b.String()
+}
+
+func (s *Section) render(b *strings.Builder) {
+	b.WriteString(s.Heading)
+	b.WriteString(s.Body)
+	for _, sub := range s.Subsections {
+		sub.render(b)
+	}
+}
+
+// String renders the section with its heading.
+func (s *Section) String() string {
+	var b strings.Builder
+	s.render(&b)
+	return b.String()
+}
+
+// Content renders the section without its heading: the body and all the subsections.
+func (s *Section) Content() string {
+	var b strings.Builder
+	b.WriteString(s.Body)
+	for _, sub := range s.Subsections {
+		sub.render(&b)
+	}
+	return b.String()
+}
+
+// IsEmpty reports whether the section has no content other than blank lines.
+func (s *Section) IsEmpty() bool {
+	return strings.TrimSpace(s.Content()) == ""
+}
+
+// Titles returns the titles of the top level sections.
+func (d *Document) Titles() []string {
+	titles := make([]string, 0, len(d.Sections))
+	for _, section := range d.Sections {
+		titles = append(titles, section.Title)
+	}
+	return titles
+}
+
+// Get returns the content of a top level section, without its heading.
+func (d *Document) Get(title string) (string, bool) {
+	section := d.TopSection(title)
+	if section == nil {
+		return "", false
+	}
+	return section.Content(), true
+}
+
+// Set replaces the content of a top level section, subsections included. A missing section is added
+// at the end of the document.
+func (d *Document) Set(title string, content string) {
+	section := d.TopSection(title)
+	if section == nil {
+		section = &Section{Level: 1, Title: title, Heading: "# " + title + "\n"}
+		if d.String() != "" {
+			d.lastBody(ensureBlankLine)
+		}
+		d.Sections = append(d.Sections, section)
+	}
+	section.Body = formatBody(content)
+	section.Subsections = nil
+}
+
+// Clear removes the content of a top level section, keeping its heading.
+func (d *Document) Clear(title string) {
+	if section := d.TopSection(title); section != nil {
+		section.Body = "\n"
+		section.Subsections = nil
+	}
+}
+
+// Append adds text at the end of a top level section. A missing section is created.
+func (d *Document) Append(title string, text string) {
+	existing, ok := d.Get(title)
+	if !ok || strings.TrimSpace(existing) == "" {
+		d.Set(title, text)
+		return
+	}
+	d.Set(title, strings.TrimRight(existing, "\r\n")+"\n"+strings.Trim(text, "\r\n"))
+}
+
+// formatBody lays out the content of a modified section: a blank line after the heading,
+// the content, and a blank line before the next heading
+func formatBody(content string) string {
+	content = strings.Trim(content, "\r\n")
+	if strings.TrimSpace(content) == "" {
+		return "\n"
+	}
+	return "\n" + content + "\n\n"
+}
+
+// lastBody updates the body of the last rendered section of the document
+func (d *Document) lastBody(update func(string) string) {
+	if len(d.Sections) == 0 {
+		d.Preamble = update(d.Preamble)
+		return
+	}
+	last := d.Sections[len(d.Sections)-1]
+	for len(last.Subsections) > 0 {
+		last = last.Subsections[len(last.Subsections)-1]
+	}
+	last.Body = update(last.Body)
+}
+
+func ensureBlankLine(text string) string {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if !strings.HasSuffix(text, "\n\n") {
+		text += "\n"
+	}
+	return text
+}
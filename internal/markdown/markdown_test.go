@@ -0,0 +1,166 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantTitles []string
+	}{
+		{"empty", "", []string{}},
+		{"preamble only", "Some text\nwithout headings", []string{}},
+		{"sections", "Intro\n\n# One\n\nBody\n\n# Two\nMore\n", []string{"One", "Two"}},
+		{"subsections", "# One\n## Sub\ntext\n### Deeper\n# Two\n", []string{"One", "Two"}},
+		{"closing hashes and spaces", "  # One ##  \ntext\n#\tTwo\n", []string{"One", "Two"}},
+		{"not headings", "#hashtag\n    # indented code\n####### seven\n", []string{}},
+		{"fenced code", "# One\n```sh\n# comment\n~~~\n```` \n~~~\n# another\n~~~~\n# Two\n", []string{"One", "Two"}},
+		{"unclosed fence", "# One\n```\n# not a heading\n", []string{"One"}},
+		{"windows line breaks", "# One\r\ntext\r\n# Two\r\n", []string{"One", "Two"}},
+		{"no final line break", "# One\ntext", []string{"One"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := Parse(test.content)
+			if got := doc.String(); got != test.content {
+				t.Errorf("String() = %q, want %q", got, test.content)
+			}
+			if got := doc.Titles(); !reflect.DeepEqual(got, test.wantTitles) {
+				t.Errorf("Titles() = %q, want %q", got, test.wantTitles)
+			}
+		})
+	}
+}
+
+func TestSectionContent(t *testing.T) {
+	doc := Parse("# One\n\nBody\n## Sub\ntext\n\n# Two\n\n")
+
+	tests := []struct {
+		title     string
+		want      string
+		wantOK    bool
+		wantEmpty bool
+	}{
+		{"One", "\nBody\n## Sub\ntext\n\n", true, false},
+		{"sub", "", false, false}, // Only top level sections are looked up
+		{" two ", "\n", true, true},
+		{"Three", "", false, false},
+	}
+	for _, test := range tests {
+		got, ok := doc.Get(test.title)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("Get(%q) = %q, %v, want %q, %v", test.title, got, ok, test.want, test.wantOK)
+		}
+		if section := doc.TopSection(test.title); ok && section.IsEmpty() != test.wantEmpty {
+			t.Errorf("TopSection(%q).IsEmpty() = %v, want %v", test.title, !test.wantEmpty, test.wantEmpty)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	const content = "Intro\n\n# One\n\nold\n## Sub\ntext\n\n# Two\n\nkept\n"
+
+	tests := []struct {
+		name string
+		edit func(doc *Document)
+		want string
+	}{
+		{
+			name: "set",
+			edit: func(doc *Document) { doc.Set("one", "new\n") },
+			want: "Intro\n\n# One\n\nnew\n\n# Two\n\nkept\n",
+		},
+		{
+			name: "set empty",
+			edit: func(doc *Document) { doc.Set("Two", "  ") },
+			want: "Intro\n\n# One\n\nold\n## Sub\ntext\n\n# Two\n\n",
+		},
+		{
+			name: "set missing section",
+			edit: func(doc *Document) { doc.Set("Three", "added") },
+			want: content + "\n# Three\n\nadded\n\n",
+		},
+		{
+			name: "clear",
+			edit: func(doc *Document) { doc.Clear("One") },
+			want: "Intro\n\n# One\n\n# Two\n\nkept\n",
+		},
+		{
+			name: "append",
+			edit: func(doc *Document) { doc.Append("Two", "\nmore\n") },
+			want: "Intro\n\n# One\n\nold\n## Sub\ntext\n\n# Two\n\nkept\nmore\n\n",
+		},
+		{
+			name: "append to missing section",
+			edit: func(doc *Document) { doc.Append("Three", "added") },
+			want: content + "\n# Three\n\nadded\n\n",
+		},
+		{
+			name: "replace section",
+			edit: func(doc *Document) { doc.ReplaceSection(Parse("# ONE\nas is").Sections[0]) },
+			want: "Intro\n\n# ONE\nas is# Two\n\nkept\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := Parse(content)
+			test.edit(doc)
+			if got := doc.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditNestedSameTitle(t *testing.T) {
+	// A subsection of an earlier section has the title of the top level section being edited
+	const content = "# Implemented Code\n\n## Verification Report\nnotes\n\n# Verification Report\n\nold\n"
+
+	tests := []struct {
+		name string
+		edit func(doc *Document)
+		want string
+	}{
+		{
+			name: "set",
+			edit: func(doc *Document) { doc.Set("Verification Report", "new") },
+			want: "# Implemented Code\n\n## Verification Report\nnotes\n\n# Verification Report\n\nnew\n\n",
+		},
+		{
+			name: "clear",
+			edit: func(doc *Document) { doc.Clear("verification report") },
+			want: "# Implemented Code\n\n## Verification Report\nnotes\n\n# Verification Report\n\n",
+		},
+		{
+			name: "append",
+			edit: func(doc *Document) { doc.Append("Verification Report", "more") },
+			want: "# Implemented Code\n\n## Verification Report\nnotes\n\n# Verification Report\n\nold\nmore\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := Parse(content)
+			if got, _ := doc.Get("Verification Report"); got != "\nold\n" {
+				t.Errorf("Get() = %q, want the top level section", got)
+			}
+			test.edit(doc)
+			if got := doc.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSetEmptyDocument(t *testing.T) {
+	doc := Parse("")
+	doc.Set("One", "text")
+	if got, want := doc.String(), "# One\n\ntext\n\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
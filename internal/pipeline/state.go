@@ -0,0 +1,158 @@
+// Package pipeline persists the progress of pipeline runs, so an interrupted run can be resumed.
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+)
+
+// Step statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSuccess   = "success"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusCancelled = "cancelled"
+)
+
+// DefaultName is the name recorded for the pipeline run without --name
+const DefaultName = "default"
+
+// State is the progress of a pipeline run.
+type State struct {
+	Pipeline   string          `json:"pipeline"`       // Name of the pipeline, DefaultName for the default one
+	Definition config.Pipeline `json:"definition"`     // Steps of the run, so it resumes with the same steps
+	Task       string          `json:"task,omitempty"` // Task the run works on
+	Branch     string          `json:"branch,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Steps      []StepState     `json:"steps"`
+}
+
+// StepState is the progress of a single step.
+type StepState struct {
+	Name       string    `json:"name"`
+	Role       string    `json:"role"`
+	Status     string    `json:"status"`
+	Sessions   []string  `json:"sessions,omitempty"`   // Agent sessions of the step, one per attempt
+	Iterations int       `json:"iterations,omitempty"` // Develop-review fix iterations run by the step
+	CostUSD    float64   `json:"cost_usd,omitempty"`   // Spent by the agents of the step
+	Tokens     int64     `json:"tokens,omitempty"`     // Input, output and cache write tokens of the agents of the step
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// New returns the state of a new run of the pipeline, with all the steps pending.
+func New(name string, definition config.Pipeline, taskID string, branch string) *State {
+	now := time.Now()
+	state := &State{Pipeline: name, Definition: definition, Task: taskID, Branch: branch, StartedAt: now, UpdatedAt: now}
+	for _, step := range definition.Steps {
+		state.Steps = append(state.Steps, StepState{Name: step.Title(), Role: step.Role, Status: StatusPending})
+	}
+	return state
+}
+
+// Path returns the state file of the pipeline runs of a task
+func Path(taskID string) string {
+	if taskID == "" {
+		taskID = DefaultName
+	}
+	return filepath.Join(config.PipelineStateDir, taskID+".json")
+}
+
+// Load reads the state of the last pipeline run of a task. It returns nil if there is none.
+func Load(taskID string) (*State, error) {
+	path := Path(taskID)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading pipeline state: %v", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if len(state.Steps) != len(state.Definition.Steps) {
+		return nil, fmt.Errorf("%s is inconsistent, remove it to start over", path)
+	}
+	return &state, nil
+}
+
+// Save writes the state of the run.
+func (s *State) Save() error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding pipeline state: %v", err)
+	}
+	if err := os.MkdirAll(config.PipelineStateDir, 0755); err != nil {
+		return fmt.Errorf("creating %s directory: %v", config.PipelineStateDir, err)
+	}
+	if err := os.WriteFile(Path(s.Task), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing pipeline state: %v", err)
+	}
+	return nil
+}
+
+// StepIndex returns the position of the step with the given name, or -1.
+func (s *State) StepIndex(name string) int {
+	for i, step := range s.Steps {
+		if step.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// FirstIncomplete returns the position of the first step that didn't succeed and wasn't skipped,
+// or the number of steps when the run is complete.
+func (s *State) FirstIncomplete() int {
+	for i, step := range s.Steps {
+		if step.Status != StatusSuccess && step.Status != StatusSkipped {
+			return i
+		}
+	}
+	return len(s.Steps)
+}
+
+// Start marks a step as running.
+func (s *State) Start(i int) {
+	s.Steps[i].Status = StatusRunning
+	s.Steps[i].StartedAt = time.Now()
+	s.Steps[i].FinishedAt = time.Time{}
+	s.Steps[i].Sessions = nil
+	s.Steps[i].Iterations = 0
+	s.Steps[i].CostUSD = 0
+	s.Steps[i].Tokens = 0
+	s.Steps[i].Error = ""
+}
+
+// Spent returns the cost and tokens of the steps of the run
+func (s *State) Spent() (float64, int64) {
+	var cost float64
+	var tokens int64
+	for _, step := range s.Steps {
+		cost += step.CostUSD
+		tokens += step.Tokens
+	}
+	return cost, tokens
+}
+
+// Finish records the outcome of a step.
+func (s *State) Finish(i int, status string, err error) {
+	s.Steps[i].Status = status
+	s.Steps[i].FinishedAt = time.Now()
+	if err != nil {
+		s.Steps[i].Error = err.Error()
+	}
+}
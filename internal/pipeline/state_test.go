@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/fynardo/astropath/config"
+)
+
+var testDefinition = config.Pipeline{Steps: []config.Step{
+	{Role: "analyst"},
+	{Role: "developer"},
+	{Role: "reviewer", Name: "final-review"},
+}}
+
+func TestNew(t *testing.T) {
+	state := New("bugfix", testDefinition, "fix-login", "fix-login-branch")
+
+	var names []string
+	for _, step := range state.Steps {
+		names = append(names, step.Name)
+		if step.Status != StatusPending {
+			t.Errorf("step %s status = %s, want pending", step.Name, step.Status)
+		}
+	}
+	if want := []string{"analyst", "developer", "final-review"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("steps = %q, want %q", names, want)
+	}
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"analyst", 0},
+		{"final-review", 2},
+		{"reviewer", -1},
+		{"tester", -1},
+	}
+	for _, test := range tests {
+		if got := state.StepIndex(test.name); got != test.want {
+			t.Errorf("StepIndex(%s) = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFirstIncomplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     int
+	}{
+		{"new run", []string{StatusPending, StatusPending, StatusPending}, 0},
+		{"failed step", []string{StatusSuccess, StatusFailed, StatusPending}, 1},
+		{"cancelled step", []string{StatusSuccess, StatusSuccess, StatusCancelled}, 2},
+		{"interrupted step", []string{StatusSuccess, StatusRunning, StatusPending}, 1},
+		{"skipped steps", []string{StatusSkipped, StatusSuccess, StatusPending}, 2},
+		{"complete", []string{StatusSuccess, StatusSkipped, StatusSuccess}, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := New(DefaultName, testDefinition, "", "")
+			for i, status := range test.statuses {
+				state.Steps[i].Status = status
+			}
+			if got := state.FirstIncomplete(); got != test.want {
+				t.Errorf("FirstIncomplete() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStartAndFinish(t *testing.T) {
+	state := New(DefaultName, testDefinition, "", "")
+	state.Steps[0].CostUSD, state.Steps[0].Tokens = 1.5, 1000
+	state.Steps[1].CostUSD, state.Steps[1].Tokens = 0.25, 500
+	if cost, tokens := state.Spent(); cost != 1.75 || tokens != 1500 {
+		t.Errorf("Spent() = %v, %d, want 1.75, 1500", cost, tokens)
+	}
+
+	// A step run again starts over
+	state.Steps[1].Sessions = []string{"s1"}
+	state.Steps[1].Iterations = 2
+	state.Steps[1].Error = "failed"
+	state.Start(1)
+	step := state.Steps[1]
+	if step.Status != StatusRunning || step.StartedAt.IsZero() || step.Sessions != nil || step.Iterations != 0 || step.CostUSD != 0 || step.Tokens != 0 || step.Error != "" {
+		t.Errorf("started step = %+v, want a fresh running step", step)
+	}
+	if cost, tokens := state.Spent(); cost != 1.5 || tokens != 1000 {
+		t.Errorf("Spent() = %v, %d, want 1.5, 1000", cost, tokens)
+	}
+
+	state.Finish(1, StatusFailed, errors.New("boom"))
+	if step := state.Steps[1]; step.Status != StatusFailed || step.Error != "boom" || step.FinishedAt.Before(step.StartedAt) {
+		t.Errorf("finished step = %+v, want failed with its error", step)
+	}
+	state.Finish(2, StatusSuccess, nil)
+	if step := state.Steps[2]; step.Status != StatusSuccess || step.Error != "" {
+		t.Errorf("finished step = %+v, want success", step)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	if state, err := Load("fix-login"); state != nil || err != nil {
+		t.Fatalf("Load() without runs = %v, %v, want nothing", state, err)
+	}
+
+	state := New("bugfix", testDefinition, "fix-login", "fix-login-branch")
+	state.Start(0)
+	state.Steps[0].Sessions = []string{"s1"}
+	state.Finish(0, StatusSuccess, nil)
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(DefaultName, testDefinition, "", "").Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load("fix-login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Pipeline != "bugfix" || loaded.Branch != "fix-login-branch" || !reflect.DeepEqual(loaded.Definition, testDefinition) {
+		t.Errorf("Load() = %+v, want the saved run", loaded)
+	}
+	if loaded.FirstIncomplete() != 1 || !reflect.DeepEqual(loaded.Steps[0].Sessions, []string{"s1"}) {
+		t.Errorf("steps = %+v, want the saved progress", loaded.Steps)
+	}
+	if other, err := Load(""); err != nil || other.Pipeline != DefaultName {
+		t.Errorf("Load() of the default task = %+v, %v", other, err)
+	}
+
+	// A state whose steps don't match its definition isn't resumed
+	if err := os.WriteFile(Path("fix-login"), []byte(`{"definition":{"steps":[{"role":"analyst"}]},"steps":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load("fix-login"); err == nil {
+		t.Error("Load() accepted an inconsistent state")
+	}
+}
+
+// chdir makes dir the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
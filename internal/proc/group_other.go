@@ -0,0 +1,31 @@
+//go:build !unix
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+)
+
+// SetGroup does nothing on this platform, the command shares the process group of Astropath
+func SetGroup(cmd *exec.Cmd) {}
+
+// SignalGroup sends a signal to a started command. Signals other than Kill may be unsupported,
+// in which case the process is killed.
+func SignalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := cmd.Process.Signal(sig); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// KillGroup kills a started command
+func KillGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// SetGroup makes the command start in its own process group, so signals reach its children too.
+// Signals from the terminal, such as Ctrl+C, then only reach Astropath, which forwards them.
+func SetGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// SignalGroup sends a signal to the process group of a started command
+func SignalGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	sysSig, ok := sig.(syscall.Signal)
+	if !ok {
+		return cmd.Process.Signal(sig)
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, sysSig); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// KillGroup kills the process group of a started command
+func KillGroup(cmd *exec.Cmd) error {
+	return SignalGroup(cmd, syscall.SIGKILL)
+}
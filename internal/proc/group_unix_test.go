@@ -0,0 +1,87 @@
+//go:build unix
+
+package proc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startGroup starts a shell script in its own process group
+func startGroup(t *testing.T, script string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = t.TempDir()
+	SetGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { KillGroup(cmd) })
+	return cmd
+}
+
+// waitFile waits until the file exists
+func waitFile(t *testing.T, path string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+	}
+	t.Fatalf("%s wasn't created", path)
+}
+
+func TestSignalGroup(t *testing.T) {
+	// The child reports the signal it gets, the parent just waits for it
+	cmd := startGroup(t, `sh -c 'trap "touch child-stopped; exit 0" TERM; touch child-started; while :; do sleep 0.1; done' & wait`)
+	waitFile(t, filepath.Join(cmd.Dir, "child-started"))
+
+	if err := SignalGroup(cmd, syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	waitFile(t, filepath.Join(cmd.Dir, "child-stopped"))
+
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); !errors.As(err, &exitErr) || exitErr.Sys().(syscall.WaitStatus).Signal() != syscall.SIGTERM {
+		t.Errorf("Wait() = %v, want the parent terminated by SIGTERM", err)
+	}
+	// Signaling a group that is gone isn't an error
+	if err := SignalGroup(cmd, syscall.SIGTERM); err != nil {
+		t.Errorf("SignalGroup() after exit = %v", err)
+	}
+}
+
+func TestKillGroup(t *testing.T) {
+	// SIGTERM is ignored, only SIGKILL stops the command
+	cmd := startGroup(t, `trap "" TERM; touch started; while :; do sleep 0.1; done`)
+	waitFile(t, filepath.Join(cmd.Dir, "started"))
+
+	if err := SignalGroup(cmd, syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		t.Fatalf("the command exited on SIGTERM: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := KillGroup(cmd); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-exited:
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.Sys().(syscall.WaitStatus).Signal() != syscall.SIGKILL {
+			t.Errorf("Wait() = %v, want the command killed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command survived KillGroup()")
+	}
+}
@@ -0,0 +1,62 @@
+// Package proc handles the signals Astropath receives and the process groups of the commands it runs,
+// so agents and verification commands are stopped together with their children.
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// GracePeriod is the time a process has to exit after being signaled, before it is killed.
+// It is a variable so tests can shorten it.
+var GracePeriod = 10 * time.Second
+
+// SignalError is the cause of a context cancelled by a signal.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("cancelled (%s)", e.Signal)
+}
+
+// NotifyContext returns a context cancelled when Astropath receives SIGINT or SIGTERM, with a SignalError as its cause.
+// Until stop is called, the signals don't terminate Astropath, so it can stop its processes and save its state.
+func NotifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			cancel(&SignalError{Signal: sig})
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(context.Canceled)
+	}
+}
+
+// Signal returns the signal that cancelled the context, SIGTERM when it was cancelled otherwise
+func Signal(ctx context.Context) os.Signal {
+	var sigErr *SignalError
+	if errors.As(context.Cause(ctx), &sigErr) {
+		return sigErr.Signal
+	}
+	return syscall.SIGTERM
+}
+
+// Interrupted reports whether the error comes from a signal, e.g. a run stopped with Ctrl+C
+func Interrupted(err error) bool {
+	var sigErr *SignalError
+	return errors.As(err, &sigErr)
+}
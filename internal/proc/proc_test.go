@@ -0,0 +1,46 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNotifyContext(t *testing.T) {
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+
+	// The signal cancels the context instead of terminating the test
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context wasn't cancelled by SIGINT")
+	}
+
+	err := fmt.Errorf("running the developer: %w", context.Cause(ctx))
+	if !Interrupted(err) {
+		t.Errorf("Interrupted(%v) = false, want true", err)
+	}
+	if sig := Signal(ctx); sig != os.Interrupt {
+		t.Errorf("Signal() = %v, want %v", sig, os.Interrupt)
+	}
+}
+
+func TestNotifyContextStopped(t *testing.T) {
+	ctx, stop := NotifyContext(context.Background())
+	stop()
+
+	<-ctx.Done()
+	if Interrupted(context.Cause(ctx)) {
+		t.Errorf("Interrupted() = true for a context stopped without a signal")
+	}
+	if sig := Signal(ctx); sig != syscall.SIGTERM {
+		t.Errorf("Signal() = %v, want %v", sig, syscall.SIGTERM)
+	}
+}
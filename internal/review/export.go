@@ -0,0 +1,245 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// Formats returns the formats findings can be exported to
+func Formats() []string {
+	return []string{FormatJSON, FormatSARIF}
+}
+
+// Report is a review to export: its findings and what was reviewed.
+type Report struct {
+	Range      string    `json:"range"`                 // Commit range that was reviewed, e.g. main...feature
+	BaseCommit string    `json:"base_commit,omitempty"` // Commit the range starts from
+	HeadCommit string    `json:"head_commit,omitempty"` // Commit the range ends at
+	Branch     string    `json:"branch,omitempty"`      // Reviewed branch, empty when a commit range was given
+	Repository string    `json:"repository,omitempty"`  // URL of the origin remote
+	Root       string    `json:"-"`                     // Root of the working copy, the files of the findings are relative to it
+	Findings   []Finding `json:"-"`
+}
+
+// RelativePath returns the path of a file relative to the repository root, with forward slashes.
+// Relative paths are taken as relative to the root already.
+func RelativePath(file string, root string) string {
+	if file == "" {
+		return ""
+	}
+	if filepath.IsAbs(file) && root != "" {
+		if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(file))
+}
+
+// Write encodes the report in the given format
+func Write(w io.Writer, format string, report Report) error {
+	var doc any
+	switch format {
+	case FormatJSON:
+		doc = jsonReport(report)
+	case FormatSARIF:
+		doc = sarifReport(report)
+	default:
+		return fmt.Errorf("unknown format '%s' (available: %s)", format, strings.Join(Formats(), ", "))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("encoding the %s report: %v", format, err)
+	}
+	return nil
+}
+
+// jsonFinding is a finding of the JSON export, with the id of its rule
+type jsonFinding struct {
+	RuleID string `json:"rule_id"`
+	Finding
+}
+
+func jsonReport(report Report) any {
+	findings := make([]jsonFinding, 0, len(report.Findings))
+	for _, finding := range report.Findings {
+		findings = append(findings, jsonFinding{RuleID: finding.RuleID(), Finding: finding})
+	}
+	return struct {
+		Report
+		Findings []jsonFinding `json:"findings"`
+	}{report, findings}
+}
+
+// SARIF 2.1.0 documents, limited to what the export uses.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifSourceRoot is the base of the file locations, the root of the repository
+const sarifSourceRoot = "SRCROOT"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool                     sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs       map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	VersionControlProvenance []sarifVersionControl            `json:"versionControlProvenance,omitempty"`
+	Results                  []sarifResult                    `json:"results"`
+	Properties               map[string]string                `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	} `json:"driver"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifVersionControl struct {
+	RepositoryURI string `json:"repositoryUri"`
+	RevisionID    string `json:"revisionId,omitempty"`
+	Branch        string `json:"branch,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	RuleIndex  int               `json:"ruleIndex"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel maps the severities to the SARIF levels
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityMajor:
+		return "error"
+	case SeverityMinor:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func sarifReport(report Report) any {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = "Astropath"
+	run.Tool.Driver.InformationURI = "https://github.com/fynardo/astropath"
+
+	// File locations are relative to the root, which viewers only know when it is defined
+	baseID := ""
+	if report.Root != "" {
+		baseID = sarifSourceRoot
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{sarifSourceRoot: {URI: directoryURI(report.Root)}}
+	}
+
+	// One rule per category, in a stable order
+	categories := Categories()
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ruleIndex := map[string]int{}
+	for i, name := range names {
+		ruleIndex[name] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               Finding{Category: name}.RuleID(),
+			Name:             name,
+			ShortDescription: sarifMessage{Text: categories[name]},
+		})
+	}
+
+	for _, finding := range report.Findings {
+		result := sarifResult{
+			RuleID:     finding.RuleID(),
+			RuleIndex:  ruleIndex[finding.Category],
+			Level:      sarifLevel(finding.Severity),
+			Message:    sarifMessage{Text: finding.Message},
+			Properties: map[string]string{"severity": finding.Severity},
+		}
+		if finding.Suggestion != "" {
+			result.Properties["suggestion"] = finding.Suggestion
+		}
+		if finding.File != "" {
+			location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: finding.File, URIBaseID: baseID}}
+			if finding.Line > 0 {
+				location.Region = &sarifRegion{StartLine: finding.Line}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: location}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	if report.Repository != "" {
+		run.VersionControlProvenance = []sarifVersionControl{{RepositoryURI: report.Repository, RevisionID: report.HeadCommit, Branch: report.Branch}}
+	}
+	run.Properties = map[string]string{"commitRange": report.Range}
+	if report.BaseCommit != "" {
+		run.Properties["baseCommit"] = report.BaseCommit
+	}
+	if report.HeadCommit != "" {
+		run.Properties["headCommit"] = report.HeadCommit
+	}
+
+	return sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}}
+}
+
+// directoryURI returns the file URI of a directory, with the trailing slash SARIF requires for base URIs
+func directoryURI(dir string) string {
+	path := filepath.ToSlash(dir)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letters, e.g. /C:/repo
+	}
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
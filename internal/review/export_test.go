@@ -0,0 +1,168 @@
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testReport = Report{
+	Range:      "main...feature",
+	BaseCommit: "aaa",
+	HeadCommit: "bbb",
+	Branch:     "feature",
+	Repository: "https://example.com/repo.git",
+	Root:       filepath.FromSlash("/repo"),
+	Findings: []Finding{
+		{Severity: SeverityMajor, Category: "security", File: "cmd/root.go", Line: 42, Message: "m1", Suggestion: "s1"},
+		{Severity: SeverityMinor, Category: "tests", File: "go.mod", Message: "m2"},
+		{Severity: SeveritySuggestion, Category: CategoryGeneral, Message: "m3"},
+	},
+}
+
+// export writes the report and decodes it back
+func export(t *testing.T, format string, report Report) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, format, report); err != nil {
+		t.Fatalf("Write(%s) = %v", format, err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Write(%s) wrote invalid JSON: %v", format, err)
+	}
+	return doc
+}
+
+func TestWriteJSON(t *testing.T) {
+	doc := export(t, FormatJSON, testReport)
+
+	if doc["range"] != "main...feature" || doc["base_commit"] != "aaa" || doc["head_commit"] != "bbb" || doc["branch"] != "feature" {
+		t.Errorf("report = %v, want the reviewed range", doc)
+	}
+	findings := doc["findings"].([]any)
+	if len(findings) != 3 {
+		t.Fatalf("findings = %v, want 3", findings)
+	}
+	want := map[string]any{
+		"rule_id": "astropath/security", "severity": "major", "category": "security",
+		"file": "cmd/root.go", "line": 42.0, "message": "m1", "suggestion": "s1",
+	}
+	if !reflect.DeepEqual(findings[0], want) {
+		t.Errorf("finding = %v, want %v", findings[0], want)
+	}
+
+	if empty := export(t, FormatJSON, Report{Range: "HEAD~1..HEAD"}); !reflect.DeepEqual(empty["findings"], []any{}) {
+		t.Errorf("findings of an empty report = %v, want []", empty["findings"])
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	doc := export(t, FormatSARIF, testReport)
+	if doc["version"] != "2.1.0" {
+		t.Errorf("version = %v, want 2.1.0", doc["version"])
+	}
+	run := doc["runs"].([]any)[0].(map[string]any)
+
+	rules := run["tool"].(map[string]any)["driver"].(map[string]any)["rules"].([]any)
+	if len(rules) != len(Categories()) {
+		t.Errorf("%d rules, want one per category", len(rules))
+	}
+
+	tests := []struct {
+		level    string
+		location string
+		line     any
+	}{
+		{"error", "cmd/root.go", 42.0},
+		{"warning", "go.mod", nil},
+		{"note", "", nil},
+	}
+	results := run["results"].([]any)
+	for i, test := range tests {
+		result := results[i].(map[string]any)
+		if result["level"] != test.level {
+			t.Errorf("result %d level = %v, want %v", i, result["level"], test.level)
+		}
+		rule := rules[int(result["ruleIndex"].(float64))].(map[string]any)
+		if rule["id"] != result["ruleId"] {
+			t.Errorf("result %d ruleIndex points to %v, want %v", i, rule["id"], result["ruleId"])
+		}
+
+		locations, _ := result["locations"].([]any)
+		if test.location == "" {
+			if locations != nil {
+				t.Errorf("result %d locations = %v, want none", i, locations)
+			}
+			continue
+		}
+		physical := locations[0].(map[string]any)["physicalLocation"].(map[string]any)
+		artifact := physical["artifactLocation"].(map[string]any)
+		if artifact["uri"] != test.location || artifact["uriBaseId"] != "SRCROOT" {
+			t.Errorf("result %d artifact = %v, want %s", i, artifact, test.location)
+		}
+		var line any
+		if region, ok := physical["region"].(map[string]any); ok {
+			line = region["startLine"]
+		}
+		if line != test.line {
+			t.Errorf("result %d line = %v, want %v", i, line, test.line)
+		}
+	}
+
+	bases := map[string]any{"SRCROOT": map[string]any{"uri": "file:///repo/"}}
+	if !reflect.DeepEqual(run["originalUriBaseIds"], bases) {
+		t.Errorf("originalUriBaseIds = %v, want %v", run["originalUriBaseIds"], bases)
+	}
+
+	provenance := run["versionControlProvenance"].([]any)[0].(map[string]any)
+	if provenance["repositoryUri"] != testReport.Repository || provenance["revisionId"] != "bbb" {
+		t.Errorf("versionControlProvenance = %v", provenance)
+	}
+	properties := map[string]any{"commitRange": "main...feature", "baseCommit": "aaa", "headCommit": "bbb"}
+	if !reflect.DeepEqual(run["properties"], properties) {
+		t.Errorf("properties = %v, want %v", run["properties"], properties)
+	}
+}
+
+func TestWriteSARIFWithoutRoot(t *testing.T) {
+	report := testReport
+	report.Root = ""
+	run := export(t, FormatSARIF, report)["runs"].([]any)[0].(map[string]any)
+	if bases, ok := run["originalUriBaseIds"]; ok {
+		t.Errorf("originalUriBaseIds = %v, want none without a root", bases)
+	}
+	location := run["results"].([]any)[0].(map[string]any)["locations"].([]any)[0].(map[string]any)
+	artifact := location["physicalLocation"].(map[string]any)["artifactLocation"].(map[string]any)
+	if want := map[string]any{"uri": "cmd/root.go"}; !reflect.DeepEqual(artifact, want) {
+		t.Errorf("artifact = %v, want %v", artifact, want)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "xml", testReport); err == nil || buf.Len() > 0 {
+		t.Errorf("Write(xml) = %v, wrote %q", err, buf.String())
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"", ""},
+		{"cmd/root.go", "cmd/root.go"},
+		{"./cmd/../go.mod", "go.mod"},
+		{filepath.FromSlash("/repo/cmd/root.go"), "cmd/root.go"},
+		{filepath.FromSlash("/other/file.go"), "/other/file.go"},
+	}
+	for _, test := range tests {
+		if got := RelativePath(test.file, root); got != test.want {
+			t.Errorf("RelativePath(%q) = %q, want %q", test.file, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package review reads the machine-readable findings the reviewer writes in its section of the context file.
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severities of a finding, from the most to the least important
+const (
+	SeverityMajor      = "major"
+	SeverityMinor      = "minor"
+	SeveritySuggestion = "suggestion"
+)
+
+// CategoryGeneral is the category of findings without a known one
+const CategoryGeneral = "general"
+
+// Categories returns the categories of findings the reviewer is asked to use. Each one is a rule of the exports.
+func Categories() map[string]string {
+	return map[string]string{
+		"correctness":     "Logic errors and behavior that doesn't match the task",
+		"security":        "Vulnerabilities and unsafe handling of data",
+		"performance":     "Inefficient code",
+		"maintainability": "Code that is hard to read, change or reuse",
+		"style":           "Formatting, naming and typing mistakes",
+		"tests":           "Missing or wrong tests",
+		"docs":            "Missing or outdated documentation",
+		CategoryGeneral:   "Other findings",
+	}
+}
+
+// FencedBlockInfo is the info string of the fenced code block holding the findings: ```json findings
+const FencedBlockInfo = "json findings"
+
+// Severities returns the severities, from the most to the least important
+func Severities() []string {
+	return []string{SeverityMajor, SeverityMinor, SeveritySuggestion}
+}
+
+// Finding is a single issue reported by the reviewer.
+type Finding struct {
+	Severity   string `json:"severity"`
+	Category   string `json:"category,omitempty"`
+	File       string `json:"file,omitempty"` // Path relative to the repository root
+	Line       int    `json:"line,omitempty"` // 0 when unknown
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"` // How to fix it
+}
+
+// Location returns where the finding is, e.g. "cmd/root.go:42", or an empty string
+func (f Finding) Location() string {
+	if f.File == "" {
+		return ""
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+// RuleID returns the id of the rule the finding belongs to in the exports, e.g. "astropath/security"
+func (f Finding) RuleID() string {
+	return "astropath/" + f.Category
+}
+
+// rank orders the severities, higher is more important. Unknown severities rank 0.
+func rank(severity string) int {
+	switch strings.ToLower(severity) {
+	case SeverityMajor:
+		return 3
+	case SeverityMinor:
+		return 2
+	case SeveritySuggestion:
+		return 1
+	}
+	return 0
+}
+
+// ValidSeverity reports whether the severity is one of Severities.
+func ValidSeverity(severity string) bool {
+	return rank(severity) > 0
+}
+
+// AtLeast reports whether the finding is as important as the given severity, or more.
+func (f Finding) AtLeast(severity string) bool {
+	return rank(f.Severity) >= rank(severity)
+}
+
+var fenceRe = regexp.MustCompile("^ {0,3}(```+|~~~+)\\s*(.*)$")
+
+// Parse extracts the findings from the content of the review section.
+// It returns false when the content has no findings block.
+func Parse(content string) ([]Finding, bool, error) {
+	var block strings.Builder
+	var fence string
+	found, inside := false, false
+
+	for _, line := range strings.Split(content, "\n") {
+		match := fenceRe.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if !inside {
+			if match != nil && strings.Join(strings.Fields(match[2]), " ") == FencedBlockInfo {
+				fence, inside, found = match[1], true, true
+			}
+			continue
+		}
+		if match != nil && strings.HasPrefix(match[1], fence[:1]) && len(match[1]) >= len(fence) && match[2] == "" {
+			break
+		}
+		block.WriteString(line + "\n")
+	}
+	if !found {
+		return nil, false, nil
+	}
+
+	var findings []Finding
+	if err := json.Unmarshal([]byte(block.String()), &findings); err != nil {
+		return nil, true, fmt.Errorf("invalid findings block: %v", err)
+	}
+	for i, finding := range findings {
+		if !ValidSeverity(finding.Severity) {
+			return nil, true, fmt.Errorf("invalid severity '%s' in finding %d (available: %s)", finding.Severity, i+1, strings.Join(Severities(), ", "))
+		}
+		findings[i].Severity = strings.ToLower(finding.Severity)
+		findings[i].Category = strings.ToLower(strings.TrimSpace(finding.Category))
+		if _, ok := Categories()[findings[i].Category]; !ok {
+			findings[i].Category = CategoryGeneral
+		}
+	}
+	return findings, true, nil
+}
+
+// Count returns the number of findings of each severity.
+func Count(findings []Finding) map[string]int {
+	counts := map[string]int{}
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	return counts
+}
@@ -0,0 +1,116 @@
+package review
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		want      []Finding
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name:    "no block",
+			content: "Looks good.\n```json\n[]\n```\n",
+		},
+		{
+			name:      "empty block",
+			content:   "Looks good.\n\n```json findings\n[]\n```\n",
+			want:      []Finding{},
+			wantFound: true,
+		},
+		{
+			name: "findings",
+			content: "Review\n\n```json findings\n" +
+				`[{"severity":"Major","category":" Security ","file":"cmd/root.go","line":42,"message":"m1","suggestion":"s1"},` + "\n" +
+				`{"severity":"suggestion","category":"naming","message":"m2"}]` + "\n```\n\nMore text\n",
+			want: []Finding{
+				{Severity: SeverityMajor, Category: "security", File: "cmd/root.go", Line: 42, Message: "m1", Suggestion: "s1"},
+				{Severity: SeveritySuggestion, Category: CategoryGeneral, Message: "m2"},
+			},
+			wantFound: true,
+		},
+		{
+			name:      "longer fence and info spacing",
+			content:   "~~~~  json   findings\n[{\"severity\":\"minor\",\"message\":\"has ``` inside\"}]\n~~~~\n",
+			want:      []Finding{{Severity: SeverityMinor, Category: CategoryGeneral, Message: "has ``` inside"}},
+			wantFound: true,
+		},
+		{
+			name:      "windows line breaks",
+			content:   "```json findings\r\n[{\"severity\":\"minor\",\"message\":\"m\"}]\r\n```\r\n",
+			want:      []Finding{{Severity: SeverityMinor, Category: CategoryGeneral, Message: "m"}},
+			wantFound: true,
+		},
+		{
+			name:      "invalid JSON",
+			content:   "```json findings\n[{\"severity\":}]\n```\n",
+			wantFound: true,
+			wantErr:   true,
+		},
+		{
+			name:      "invalid severity",
+			content:   "```json findings\n[{\"severity\":\"blocker\",\"message\":\"m\"}]\n```\n",
+			wantFound: true,
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			findings, found, err := Parse(test.content)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Parse() error = %v, want error %v", err, test.wantErr)
+			}
+			if found != test.wantFound {
+				t.Errorf("Parse() found = %v, want %v", found, test.wantFound)
+			}
+			if !reflect.DeepEqual(findings, test.want) {
+				t.Errorf("Parse() = %+v, want %+v", findings, test.want)
+			}
+		})
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		severity  string
+		threshold string
+		want      bool
+	}{
+		{SeverityMajor, SeverityMajor, true},
+		{SeverityMinor, SeverityMajor, false},
+		{SeverityMajor, SeveritySuggestion, true},
+		{SeveritySuggestion, SeverityMinor, false},
+		{SeverityMinor, "MINOR", true},
+	}
+	for _, test := range tests {
+		if got := (Finding{Severity: test.severity}).AtLeast(test.threshold); got != test.want {
+			t.Errorf("%s AtLeast(%s) = %v, want %v", test.severity, test.threshold, got, test.want)
+		}
+	}
+}
+
+func TestLocationAndCount(t *testing.T) {
+	findings := []Finding{
+		{Severity: SeverityMajor, File: "a.go", Line: 3},
+		{Severity: SeverityMajor, File: "b.go"},
+		{Severity: SeverityMinor},
+	}
+
+	locations := []string{"a.go:3", "b.go", ""}
+	for i, finding := range findings {
+		if got := finding.Location(); got != locations[i] {
+			t.Errorf("Location() = %q, want %q", got, locations[i])
+		}
+	}
+
+	want := map[string]int{SeverityMajor: 2, SeverityMinor: 1}
+	if got := Count(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("Count() = %v, want %v", got, want)
+	}
+}
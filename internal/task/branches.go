@@ -0,0 +1,75 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fynardo/astropath/config"
+)
+
+// loadBranches reads the branch to task bindings
+func loadBranches() (map[string]string, error) {
+	bindings := map[string]string{}
+	data, err := os.ReadFile(config.BranchTasksPath)
+	if os.IsNotExist(err) {
+		return bindings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading branch bindings: %v", err)
+	}
+	if err := json.Unmarshal(data, &bindings); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", config.BranchTasksPath, err)
+	}
+	return bindings, nil
+}
+
+// ForBranch returns the task bound to a branch, or an empty string if it has none.
+func ForBranch(branch string) (string, error) {
+	bindings, err := loadBranches()
+	if err != nil {
+		return "", err
+	}
+	return bindings[branch], nil
+}
+
+// Branches returns the branches bound to a task.
+func Branches(id string) ([]string, error) {
+	bindings, err := loadBranches()
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for branch, taskID := range bindings {
+		if taskID == id {
+			branches = append(branches, branch)
+		}
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
+// Bind attaches a task to a branch, so the task is selected whenever the branch is checked out.
+func Bind(id string, branch string) error {
+	if _, err := Get(id); err != nil {
+		return err
+	}
+	bindings, err := loadBranches()
+	if err != nil {
+		return err
+	}
+	bindings[branch] = id
+
+	data, err := json.MarshalIndent(bindings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding branch bindings: %v", err)
+	}
+	if err := os.MkdirAll(config.AstropathDir, 0755); err != nil {
+		return fmt.Errorf("creating %s directory: %v", config.AstropathDir, err)
+	}
+	if err := os.WriteFile(config.BranchTasksPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing branch bindings: %v", err)
+	}
+	return nil
+}
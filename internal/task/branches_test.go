@@ -0,0 +1,45 @@
+package task
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fynardo/astropath/internal/git/gittest"
+)
+
+func TestBind(t *testing.T) {
+	gittest.Chdir(t, t.TempDir())
+
+	if id, err := ForBranch("feature"); err != nil || id != "" {
+		t.Fatalf("ForBranch() without bindings = %q, %v, want none", id, err)
+	}
+	if err := Bind("parser", "feature"); err == nil {
+		t.Error("Bind() accepted a missing task")
+	}
+
+	for _, id := range []string{"parser", "docs"} {
+		if _, err := Create(id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bindings := map[string]string{"feature": "parser", "feature-2": "parser", "docs-branch": "docs"}
+	for branch, id := range bindings {
+		if err := Bind(id, branch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Binding again moves the branch to the other task
+	if err := Bind("docs", "feature-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := ForBranch("feature"); err != nil || id != "parser" {
+		t.Errorf("ForBranch(feature) = %q, %v, want parser", id, err)
+	}
+	if branches, err := Branches("parser"); err != nil || !reflect.DeepEqual(branches, []string{"feature"}) {
+		t.Errorf("Branches(parser) = %v, %v, want [feature]", branches, err)
+	}
+	if branches, err := Branches("docs"); err != nil || !reflect.DeepEqual(branches, []string{"docs-branch", "feature-2"}) {
+		t.Errorf("Branches(docs) = %v, %v, want [docs-branch feature-2]", branches, err)
+	}
+}
@@ -0,0 +1,67 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+)
+
+// Session is an agent session a role ran for a task, which can be resumed to continue the conversation.
+type Session struct {
+	ID     string    `json:"id"`
+	Branch string    `json:"branch,omitempty"` // Branch the agent worked on
+	Time   time.Time `json:"time"`
+}
+
+// loadSessions reads the last sessions by task and role. The default ASTROPATH.md task has an empty id.
+func loadSessions() (map[string]map[string]Session, error) {
+	sessions := map[string]map[string]Session{}
+	data, err := os.ReadFile(config.SessionsPath)
+	if os.IsNotExist(err) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading agent sessions: %v", err)
+	}
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", config.SessionsPath, err)
+	}
+	return sessions, nil
+}
+
+// LastSession returns the last session of a role for a task, and whether there is one.
+func LastSession(id string, role string) (Session, bool, error) {
+	sessions, err := loadSessions()
+	if err != nil {
+		return Session{}, false, err
+	}
+	session, ok := sessions[id][role]
+	return session, ok, nil
+}
+
+// RecordSession makes the session the last one of the role for the task.
+func RecordSession(id string, role string, session Session) error {
+	sessions, err := loadSessions()
+	if err != nil {
+		return err
+	}
+	if sessions[id] == nil {
+		sessions[id] = map[string]Session{}
+	}
+	sessions[id][role] = session
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding agent sessions: %v", err)
+	}
+	if err := os.MkdirAll(config.AstropathDir, 0755); err != nil {
+		return fmt.Errorf("creating %s directory: %v", config.AstropathDir, err)
+	}
+	if err := os.WriteFile(config.SessionsPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing agent sessions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git/gittest"
+)
+
+func TestRecordSession(t *testing.T) {
+	gittest.Chdir(t, t.TempDir())
+
+	if _, ok, err := LastSession("parser", "developer"); err != nil || ok {
+		t.Fatalf("LastSession() without sessions = %v, %v, want none", ok, err)
+	}
+
+	at := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	records := []struct {
+		task    string
+		role    string
+		session Session
+	}{
+		{"parser", "developer", Session{ID: "s1", Branch: "feature", Time: at}},
+		{"parser", "reviewer", Session{ID: "s2", Branch: "feature", Time: at}},
+		{"", "developer", Session{ID: "s3", Time: at}}, // The default ASTROPATH.md task
+		{"parser", "developer", Session{ID: "s4", Branch: "feature-2", Time: at.Add(time.Hour)}},
+	}
+	for _, record := range records {
+		if err := RecordSession(record.task, record.role, record.session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		task string
+		role string
+		want Session
+	}{
+		{"parser", "developer", records[3].session},
+		{"parser", "reviewer", records[1].session},
+		{"", "developer", records[2].session},
+	}
+	for _, test := range tests {
+		got, ok, err := LastSession(test.task, test.role)
+		if err != nil || !ok || got != test.want {
+			t.Errorf("LastSession(%q, %s) = %+v, %v, %v, want %+v", test.task, test.role, got, ok, err, test.want)
+		}
+	}
+	if _, ok, _ := LastSession("parser", "tester"); ok {
+		t.Error("LastSession() found a session of a role that never ran")
+	}
+}
+
+func TestLoadSessionsInvalid(t *testing.T) {
+	gittest.Chdir(t, t.TempDir())
+	if err := os.MkdirAll(config.AstropathDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.SessionsPath, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LastSession("parser", "developer"); err == nil {
+		t.Error("LastSession() accepted an invalid sessions file")
+	}
+	if err := RecordSession("parser", "developer", Session{ID: "s1"}); err == nil {
+		t.Error("RecordSession() overwrote an invalid sessions file")
+	}
+}
@@ -0,0 +1,131 @@
+// Package task manages the task workspace: one ASTROPATH.md-like context file per task.
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+)
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Task is a task context file in the workspace.
+type Task struct {
+	ID      string
+	Path    string
+	ModTime time.Time
+}
+
+// ValidID reports whether the id can be used as a task id.
+func ValidID(id string) bool {
+	return validID.MatchString(id) && !strings.HasSuffix(id, ".md")
+}
+
+// Path returns the context file of a task.
+func Path(id string) string {
+	return filepath.Join(config.TasksDir, id+".md")
+}
+
+// Get returns an existing task.
+func Get(id string) (Task, error) {
+	if !ValidID(id) {
+		return Task{}, fmt.Errorf("invalid task id '%s'", id)
+	}
+	info, err := os.Stat(Path(id))
+	if os.IsNotExist(err) {
+		return Task{}, fmt.Errorf("task '%s' does not exist, create it with 'astropath task new %s'", id, id)
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("reading task '%s': %v", id, err)
+	}
+	return Task{ID: id, Path: Path(id), ModTime: info.ModTime()}, nil
+}
+
+// Create adds a new task with the given initial content.
+func Create(id string, content string) (Task, error) {
+	if !ValidID(id) {
+		return Task{}, fmt.Errorf("invalid task id '%s', use letters, digits, '.', '_' and '-'", id)
+	}
+	if err := os.MkdirAll(config.TasksDir, 0755); err != nil {
+		return Task{}, fmt.Errorf("creating tasks directory: %v", err)
+	}
+
+	f, err := os.OpenFile(Path(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return Task{}, fmt.Errorf("task '%s' already exists", id)
+	}
+	if err != nil {
+		return Task{}, fmt.Errorf("creating task '%s': %v", id, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		return Task{}, fmt.Errorf("writing task '%s': %v", id, err)
+	}
+	return Get(id)
+}
+
+// List returns all the tasks sorted by id.
+func List() ([]Task, error) {
+	entries, err := os.ReadDir(config.TasksDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tasks directory: %v", err)
+	}
+
+	var tasks []Task
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".md")
+		if entry.IsDir() || !ok || !ValidID(id) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		tasks = append(tasks, Task{ID: id, Path: Path(id), ModTime: info.ModTime()})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+// Active returns the id of the active task, or an empty string if none is active.
+func Active() (string, error) {
+	data, err := os.ReadFile(config.ActiveTaskPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading active task: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SetActive makes a task the active one. An empty id clears the active task.
+func SetActive(id string) error {
+	if id == "" {
+		if err := os.Remove(config.ActiveTaskPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("clearing active task: %v", err)
+		}
+		return nil
+	}
+
+	if _, err := Get(id); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(config.AstropathDir, 0755); err != nil {
+		return fmt.Errorf("creating %s directory: %v", config.AstropathDir, err)
+	}
+	if err := os.WriteFile(config.ActiveTaskPath, []byte(id+"\n"), 0644); err != nil {
+		return fmt.Errorf("writing active task: %v", err)
+	}
+	return nil
+}
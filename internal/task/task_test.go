@@ -0,0 +1,97 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/git/gittest"
+)
+
+func TestCreate(t *testing.T) {
+	gittest.Chdir(t, t.TempDir())
+
+	created, err := Create("parser-fix", "# Issue Explanation\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(".astropath", "tasks", "parser-fix.md")
+	if created.ID != "parser-fix" || created.Path != want {
+		t.Errorf("Create() = %+v, want parser-fix in %s", created, want)
+	}
+	if data, err := os.ReadFile(want); err != nil || string(data) != "# Issue Explanation\n" {
+		t.Errorf("task file = %q (%v), want the initial content", data, err)
+	}
+
+	if _, err := Create("parser-fix", ""); err == nil {
+		t.Error("Create() overwrote an existing task")
+	}
+	for _, id := range []string{"", "../escape", "-flag", "task.md", "with space"} {
+		if _, err := Create(id, ""); err == nil {
+			t.Errorf("Create(%q) accepted an invalid id", id)
+		}
+	}
+	if _, err := Get("missing"); err == nil {
+		t.Error("Get() found a missing task")
+	}
+}
+
+func TestList(t *testing.T) {
+	gittest.Chdir(t, t.TempDir())
+
+	if tasks, err := List(); err != nil || tasks != nil {
+		t.Fatalf("List() without a workspace = %v, %v, want no tasks", tasks, err)
+	}
+	for _, id := range []string{"zeta", "alpha", "v1.2"} {
+		if _, err := Create(id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Files that aren't task context files are ignored
+	if err := os.Mkdir(filepath.Join(config.TasksDir, "notes.md"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config.TasksDir, "README.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	if want := []string{"alpha", "v1.2", "zeta"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("List() = %v, want %v", ids, want)
+	}
+}
+
+func TestActive(t *testing.T) {
+	gittest.Chdir(t, t.TempDir())
+
+	if id, err := Active(); err != nil || id != "" {
+		t.Fatalf("Active() = %q, %v, want no active task", id, err)
+	}
+	if err := SetActive("missing"); err == nil {
+		t.Error("SetActive() accepted a missing task")
+	}
+	if _, err := Create("parser", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetActive("parser"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := Active(); err != nil || id != "parser" {
+		t.Errorf("Active() = %q, %v, want parser", id, err)
+	}
+	if err := SetActive(""); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := Active(); err != nil || id != "" {
+		t.Errorf("Active() after clearing = %q, %v, want no active task", id, err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package usage keeps a local ledger with the tokens, cost and time spent by every agent run.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Run statuses recorded in the ledger
+const (
+	StatusSuccess   = "success"
+	StatusError     = "error"
+	StatusCancelled = "cancelled" // Stopped by a signal, e.g. Ctrl+C
+	StatusLimit     = "limit"     // Stopped at one of its limits: time, turns, cost or tokens
+)
+
+// Record is a single agent run in the ledger.
+type Record struct {
+	Time                time.Time `json:"time"`
+	Command             string    `json:"command"`
+	Role                string    `json:"role"`
+	Task                string    `json:"task,omitempty"`
+	Branch              string    `json:"branch,omitempty"`
+	Backend             string    `json:"backend"`
+	Model               string    `json:"model,omitempty"`
+	SessionID           string    `json:"session_id,omitempty"`
+	InputTokens         int64     `json:"input_tokens"`
+	OutputTokens        int64     `json:"output_tokens"`
+	CacheCreationTokens int64     `json:"cache_creation_tokens"`
+	CacheReadTokens     int64     `json:"cache_read_tokens"`
+	CostUSD             float64   `json:"cost_usd"`
+	CostEstimated       bool      `json:"cost_estimated,omitempty"` // The agent was stopped before reporting its cost
+	NumTurns            int       `json:"num_turns"`
+	DurationMs          int64     `json:"duration_ms"`
+	Status              string    `json:"status"`
+	Error               string    `json:"error,omitempty"`
+}
+
+// Append adds a record at the end of the ledger, creating it if needed.
+func Append(path string, record Record) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating ledger directory: %v", err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding usage record: %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening ledger: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing ledger: %v", err)
+	}
+	return nil
+}
+
+// Load reads all the records of the ledger. A missing ledger has no records.
+func Load(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening ledger: %v", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("parsing ledger line %d: %v", lineNum, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading ledger: %v", err)
+	}
+	return records, nil
+}
+
+// SpentTokens returns the tokens counted against the token limits: input, output and cache writes
+func (r Record) SpentTokens() int64 {
+	return r.InputTokens + r.OutputTokens + r.CacheCreationTokens
+}
+
+// Summary aggregates the records that share the same key.
+type Summary struct {
+	Key                 string
+	Runs                int
+	Failed              int
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	CostUSD             float64
+	Duration            time.Duration
+}
+
+// Fields records can be aggregated by
+var groupFields = map[string]func(Record) string{
+	"day":     func(r Record) string { return r.Time.Local().Format("2006-01-02") },
+	"role":    func(r Record) string { return r.Role },
+	"task":    func(r Record) string { return r.Task },
+	"branch":  func(r Record) string { return r.Branch },
+	"command": func(r Record) string { return r.Command },
+	"model":   func(r Record) string { return r.Model },
+}
+
+// GroupFields returns the names of the fields records can be aggregated by.
+func GroupFields() []string {
+	names := make([]string, 0, len(groupFields))
+	for name := range groupFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Aggregate groups the records by the given fields and sums their usage.
+// Summaries are sorted by key.
+func Aggregate(records []Record, by []string) ([]Summary, error) {
+	var keyFuncs []func(Record) string
+	for _, field := range by {
+		keyFunc, ok := groupFields[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q (available: %v)", field, GroupFields())
+		}
+		keyFuncs = append(keyFuncs, keyFunc)
+	}
+
+	summaries := map[string]*Summary{}
+	for _, record := range records {
+		var parts []string
+		for _, keyFunc := range keyFuncs {
+			part := keyFunc(record)
+			if part == "" {
+				part = "-"
+			}
+			parts = append(parts, part)
+		}
+		key := strings.Join(parts, " / ")
+
+		summary, ok := summaries[key]
+		if !ok {
+			summary = &Summary{Key: key}
+			summaries[key] = summary
+		}
+		summary.Runs++
+		if record.Status != StatusSuccess {
+			summary.Failed++
+		}
+		summary.InputTokens += record.InputTokens
+		summary.OutputTokens += record.OutputTokens
+		summary.CacheCreationTokens += record.CacheCreationTokens
+		summary.CacheReadTokens += record.CacheReadTokens
+		summary.CostUSD += record.CostUSD
+		summary.Duration += time.Duration(record.DurationMs) * time.Millisecond
+	}
+
+	result := make([]Summary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".astropath", "usage.jsonl")
+
+	records, err := Load(path)
+	if err != nil || records != nil {
+		t.Fatalf("Load() of a missing ledger = %v, %v, want no records", records, err)
+	}
+
+	want := []Record{
+		{Time: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC), Command: "develop", Role: "developer", Task: "t1", Backend: "claude", Model: "claude-sonnet-4-5", SessionID: "s1", InputTokens: 100, OutputTokens: 50, CacheCreationTokens: 10, CacheReadTokens: 1000, CostUSD: 0.25, NumTurns: 3, DurationMs: 1500, Status: StatusSuccess},
+		{Time: time.Date(2025, 1, 2, 11, 0, 0, 0, time.UTC), Command: "review", Role: "reviewer", Backend: "claude", CostUSD: 0.1, CostEstimated: true, Status: StatusLimit, Error: "reached the limit of 10 turns"},
+	}
+	for _, record := range want {
+		if err := Append(path, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Load() = %+v, want %+v", records, want)
+	}
+}
+
+func TestLoadInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	if err := os.WriteFile(path, []byte("{\"role\":\"developer\"}\n\n{not json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load() accepted an invalid line")
+	}
+}
+
+func TestSpentTokens(t *testing.T) {
+	record := Record{InputTokens: 100, OutputTokens: 50, CacheCreationTokens: 10, CacheReadTokens: 1000}
+	// Cache reads are cheap and don't count against the token limits
+	if got := record.SpentTokens(); got != 160 {
+		t.Errorf("SpentTokens() = %d, want 160", got)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	day := time.Date(2025, 1, 2, 12, 0, 0, 0, time.Local)
+	records := []Record{
+		{Time: day, Role: "developer", Task: "t1", InputTokens: 100, OutputTokens: 10, CostUSD: 0.5, DurationMs: 1000, Status: StatusSuccess},
+		{Time: day, Role: "developer", Task: "t2", InputTokens: 200, OutputTokens: 20, CostUSD: 0.25, CostEstimated: true, DurationMs: 2000, Status: StatusCancelled},
+		{Time: day.AddDate(0, 0, 1), Role: "reviewer", InputTokens: 50, CacheReadTokens: 500, CostUSD: 0.125, DurationMs: 500, Status: StatusSuccess},
+	}
+
+	tests := []struct {
+		name string
+		by   []string
+		want []Summary
+	}{
+		{
+			name: "by role",
+			by:   []string{"role"},
+			want: []Summary{
+				{Key: "developer", Runs: 2, Failed: 1, InputTokens: 300, OutputTokens: 30, CostUSD: 0.75, Duration: 3 * time.Second},
+				{Key: "reviewer", Runs: 1, InputTokens: 50, CacheReadTokens: 500, CostUSD: 0.125, Duration: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "by day and task",
+			by:   []string{"day", "task"},
+			want: []Summary{
+				{Key: "2025-01-02 / t1", Runs: 1, InputTokens: 100, OutputTokens: 10, CostUSD: 0.5, Duration: time.Second},
+				{Key: "2025-01-02 / t2", Runs: 1, Failed: 1, InputTokens: 200, OutputTokens: 20, CostUSD: 0.25, Duration: 2 * time.Second},
+				{Key: "2025-01-03 / -", Runs: 1, InputTokens: 50, CacheReadTokens: 500, CostUSD: 0.125, Duration: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "everything",
+			want: []Summary{
+				{Key: "", Runs: 3, Failed: 1, InputTokens: 350, OutputTokens: 30, CacheReadTokens: 500, CostUSD: 0.875, Duration: 3500 * time.Millisecond},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Aggregate(records, test.by)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Aggregate() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := Aggregate(records, []string{"color"}); err == nil {
+		t.Error("Aggregate() accepted an unknown field")
+	}
+}
@@ -0,0 +1,137 @@
+// Package verify runs the verification commands of a project, such as its build and tests,
+// and reports their outcome in the context file.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+	"github.com/fynardo/astropath/internal/proc"
+)
+
+// maxOutputLines is the number of output lines of a failing command kept in the report
+const maxOutputLines = 60
+
+// Result is the outcome of a verification command.
+type Result struct {
+	Command  config.VerifyCommand
+	Output   string // Combined stdout and stderr
+	Duration time.Duration
+	Err      error // Why the command failed, nil when it succeeded
+}
+
+// Passed reports whether the command succeeded
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Run executes a command with the shell, with the given time limit (zero for none).
+// The command runs in its own process group, which is killed on timeout or when the context is cancelled.
+func Run(ctx context.Context, command config.VerifyCommand, timeout time.Duration) Result {
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(runCtx, "sh", "-c", command.Run)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	proc.SetGroup(cmd)
+	cmd.Cancel = func() error { return proc.KillGroup(cmd) }
+	// Don't wait forever for the output of processes that left the group
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result := Result{Command: command, Output: output.String(), Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		result.Err = context.Cause(ctx)
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.Err = fmt.Errorf("exit status %d", exitErr.ExitCode())
+	default:
+		result.Err = err
+	}
+	return result
+}
+
+// Failed returns the names of the commands that failed
+func Failed(results []Result) []string {
+	var names []string
+	for _, result := range results {
+		if !result.Passed() {
+			names = append(names, result.Command.Title())
+		}
+	}
+	return names
+}
+
+// Report renders the results as the content of the verification section of the context file.
+// The output of failing commands is included, limited to its last lines.
+func Report(results []Result, branch string) string {
+	var b strings.Builder
+	status := "passed"
+	if len(Failed(results)) > 0 {
+		status = "failed"
+	}
+	fmt.Fprintf(&b, "Verification of branch '%s' on %s: %s.\n", branch, time.Now().Format("2006-01-02 15:04"), status)
+
+	for _, result := range results {
+		outcome := "passed"
+		if !result.Passed() {
+			outcome = "failed, " + result.Err.Error()
+		}
+		fmt.Fprintf(&b, "\n## %s: %s\n\n", result.Command.Title(), outcome)
+		fmt.Fprintf(&b, "Command: `%s` (%s)\n", result.Command.Run, result.Duration.Round(100*time.Millisecond))
+		if result.Passed() {
+			continue
+		}
+
+		output := strings.TrimRight(result.Output, "\r\n")
+		lines := strings.Split(output, "\n")
+		if len(lines) > maxOutputLines {
+			fmt.Fprintf(&b, "\nLast %d of %d output lines:\n", maxOutputLines, len(lines))
+			output = strings.Join(lines[len(lines)-maxOutputLines:], "\n")
+		}
+		if strings.TrimSpace(output) == "" {
+			b.WriteString("\nNo output.\n")
+			continue
+		}
+		fence := fenceFor(output)
+		fmt.Fprintf(&b, "\n%stext\n%s\n%s\n", fence, output, fence)
+	}
+	return b.String()
+}
+
+// fenceFor returns a code fence longer than any run of backticks in the text
+func fenceFor(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
@@ -0,0 +1,92 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fynardo/astropath/config"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        string
+		timeout    time.Duration
+		wantErr    string
+		wantOutput string
+	}{
+		{name: "passing", run: "echo built", wantOutput: "built\n"},
+		{name: "failing", run: "echo broken >&2; exit 3", wantErr: "exit status 3", wantOutput: "broken\n"},
+		{name: "timeout", run: "sleep 10", timeout: 100 * time.Millisecond, wantErr: "timed out after 100ms"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Run(context.Background(), config.VerifyCommand{Run: test.run}, test.timeout)
+			if result.Passed() != (test.wantErr == "") || result.Err != nil && result.Err.Error() != test.wantErr {
+				t.Errorf("Err = %v, want %q", result.Err, test.wantErr)
+			}
+			if result.Output != test.wantOutput {
+				t.Errorf("Output = %q, want %q", result.Output, test.wantOutput)
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	cause := errors.New("cancelled by the test")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(100*time.Millisecond, func() { cancel(cause) })
+
+	start := time.Now()
+	result := Run(ctx, config.VerifyCommand{Run: "sleep 10"}, 0)
+	if !errors.Is(result.Err, cause) {
+		t.Errorf("Err = %v, want the cause of the cancellation", result.Err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() returned after %s, want the command killed", elapsed)
+	}
+}
+
+func TestReport(t *testing.T) {
+	var long []string
+	for i := 1; i <= maxOutputLines+5; i++ {
+		long = append(long, fmt.Sprintf("line %d", i))
+	}
+	results := []Result{
+		{Command: config.VerifyCommand{Name: "build", Run: "go build ./..."}},
+		{Command: config.VerifyCommand{Run: "go test ./..."}, Output: strings.Join(long, "\n") + "\n", Err: errors.New("exit status 1")},
+		{Command: config.VerifyCommand{Run: "go vet ./..."}, Output: "uses ``` fences\n", Err: errors.New("exit status 1")},
+		{Command: config.VerifyCommand{Run: "true"}, Err: errors.New("exit status 2")},
+	}
+
+	report := Report(results, "feature")
+	for _, want := range []string{
+		"Verification of branch 'feature' on ",
+		": failed.\n",
+		"\n## build: passed\n\nCommand: `go build ./...` (0s)\n",
+		"\n## go test ./...: failed, exit status 1\n",
+		fmt.Sprintf("Last %d of %d output lines:\n\n```text\nline 6\n", maxOutputLines, maxOutputLines+5),
+		fmt.Sprintf("line %d\n```\n", maxOutputLines+5),
+		"\n````text\nuses ``` fences\n````\n",
+		"\n## true: failed, exit status 2\n\nCommand: `true` (0s)\n\nNo output.\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report() = %q, want it to contain %q", report, want)
+		}
+	}
+	if strings.Contains(report, "line 5\n") {
+		t.Errorf("Report() = %q, want the first output lines dropped", report)
+	}
+
+	if got := Failed(results); strings.Join(got, ",") != "go test ./...,go vet ./...,true" {
+		t.Errorf("Failed() = %v, want the failing commands", got)
+	}
+	if report := Report(results[:1], "feature"); !strings.Contains(report, ": passed.\n") {
+		t.Errorf("Report() = %q, want passed", report)
+	}
+}